@@ -0,0 +1,163 @@
+package alert
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/supervisor/history"
+)
+
+// alert event fields.
+const (
+	Suite          = "alert"
+	ActionFiring   = "firing"
+	ActionResolved = "resolved"
+)
+
+// Notifier is notified about firing and resolved alerts in addition to backends.
+type Notifier interface {
+	Notify(e *backend.EventSchema) error
+	ID() string
+}
+
+type stateKey struct {
+	rule int
+	unit string
+}
+
+type state struct {
+	since  time.Time
+	firing bool
+
+	// last sample crossing the threshold and its value.
+	last  *backend.BigQuerySchema
+	value float64
+}
+
+// staleRounds is a number of collection intervals after which a state of a unit without
+// samples is expired, e.g. when the unit was stopped or removed.
+const staleRounds = 3
+
+// NewEngine returns a new instance of Engine. interval returns the current collection interval.
+// Alert events are passed to emit, which must not block.
+func NewEngine(rules []*Rule, interval func() time.Duration, emit func(*backend.EventSchema), notifiers ...Notifier) *Engine {
+	return &Engine{
+		rules:     rules,
+		interval:  interval,
+		emit:      emit,
+		notifiers: notifiers,
+		states:    map[stateKey]*state{},
+	}
+}
+
+// Engine evaluates rules against every sample.
+type Engine struct {
+	sync.Mutex
+
+	rules     []*Rule
+	interval  func() time.Duration
+	emit      func(*backend.EventSchema)
+	notifiers []Notifier
+	states    map[stateKey]*state
+}
+
+// OnSample evaluates rules matching the sample unit and expires states of units without recent samples.
+func (e *Engine) OnSample(s *backend.BigQuerySchema) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.expire(s.Timestamp)
+
+	for i, rule := range e.rules {
+		if !rule.Match(s.Name) {
+			continue
+		}
+
+		value, err := history.Value(s, rule.Metric)
+		if err != nil {
+			continue
+		}
+
+		key := stateKey{rule: i, unit: s.Name}
+		st, ok := e.states[key]
+
+		if !rule.Crossed(value) {
+			if ok && st.firing {
+				e.fire(rule, s, value, ActionResolved, s.Timestamp.Sub(st.since))
+			}
+			delete(e.states, key)
+			continue
+		}
+
+		if !ok {
+			st = &state{since: s.Timestamp}
+			e.states[key] = st
+		}
+		st.last = s
+		st.value = value
+
+		if !st.firing && s.Timestamp.Sub(st.since) >= rule.For {
+			st.firing = true
+			e.fire(rule, s, value, ActionFiring, s.Timestamp.Sub(st.since))
+		}
+	}
+}
+
+// expire removes states not updated for staleRounds collection intervals before now, firing
+// alerts are resolved with the last value seen.
+func (e *Engine) expire(now time.Time) {
+	staleAfter := staleRounds * e.interval()
+	for key, st := range e.states {
+		if now.Sub(st.last.Timestamp) < staleAfter {
+			continue
+		}
+
+		if st.firing {
+			e.fire(e.rules[key.rule], st.last, st.value, ActionResolved, st.last.Timestamp.Sub(st.since),
+				backend.EventLabel{Key: "reason", Value: "expired"})
+		}
+		delete(e.states, key)
+	}
+}
+
+// OnEvent is a no-op, rules apply to samples only.
+func (e *Engine) OnEvent(*backend.EventSchema) {}
+
+// fire emits an alert event and notifies notifiers in the background. duration is how long
+// the threshold has been crossed, labels are added to the event.
+func (e *Engine) fire(rule *Rule, s *backend.BigQuerySchema, value float64, action string, duration time.Duration, labels ...backend.EventLabel) {
+	logrus.Warningf("Alert %s %s: unit %s %s %s", rule.Name, action, s.Name, rule.Metric, strconv.FormatFloat(value, 'f', -1, 64))
+
+	event := &backend.EventSchema{
+		Timestamp:   s.Timestamp,
+		Suite:       Suite,
+		Test:        rule.Name,
+		Action:      action,
+		Value:       value,
+		DurationSec: duration.Seconds(),
+		Hostname:    s.Hostname,
+		Instance:    s.Instance,
+		RunID:       s.RunID,
+	}
+	event.SetLabel("unit", s.Name)
+	event.SetLabel("metric", rule.Metric)
+	event.SetLabel("threshold", rule.Op+" "+strconv.FormatFloat(rule.Threshold, 'f', -1, 64))
+	for _, label := range labels {
+		event.SetLabel(label.Key, label.Value)
+	}
+
+	// the emitted event is owned by the watcher from now on, notifiers get a copy.
+	notified := *event
+	e.emit(event)
+
+	for _, n := range e.notifiers {
+		go func(n Notifier) {
+			if err := n.Notify(&notified); err != nil {
+				logrus.Errorf("Error notifying %s about alert %s: %s", n.ID(), rule.Name, err)
+			}
+		}(n)
+	}
+}
@@ -0,0 +1,117 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/mesosphere/performance/supervisor/backend"
+)
+
+// notifyTimeout bounds a single notification.
+const notifyTimeout = 10 * time.Second
+
+// NewWebhookNotifier returns a notifier which POSTs alert events as JSON to a URL.
+func NewWebhookNotifier(url string) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	return &WebhookNotifier{
+		URL:    url,
+		client: &http.Client{Timeout: notifyTimeout},
+	}, nil
+}
+
+// WebhookNotifier POSTs alert events to a URL.
+type WebhookNotifier struct {
+	URL string
+
+	client *http.Client
+}
+
+// ID returns a notifier name.
+func (w *WebhookNotifier) ID() string {
+	return fmt.Sprintf("Webhook. URL: %s", w.URL)
+}
+
+func (w *WebhookNotifier) Notify(e *backend.EventSchema) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Got %d response code from %s: %s", resp.StatusCode, w.URL, msg)
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// NewCommandNotifier returns a notifier which runs a shell command for every alert event.
+func NewCommandNotifier(command string) (*CommandNotifier, error) {
+	if command == "" {
+		return nil, errors.New("command cannot be empty")
+	}
+	return &CommandNotifier{Command: command}, nil
+}
+
+// CommandNotifier runs a command with sh -c. The event is passed as JSON on stdin and
+// in ALERT_RULE, ALERT_ACTION, ALERT_UNIT, ALERT_VALUE and ALERT_HOSTNAME environment variables.
+type CommandNotifier struct {
+	Command string
+}
+
+// ID returns a notifier name.
+func (c *CommandNotifier) ID() string {
+	return fmt.Sprintf("Command: %s", c.Command)
+}
+
+func (c *CommandNotifier) Notify(e *backend.EventSchema) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	unit := ""
+	for _, label := range e.Labels {
+		if label.Key == "unit" {
+			unit = label.Value
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"ALERT_RULE="+e.Test,
+		"ALERT_ACTION="+e.Action,
+		"ALERT_UNIT="+unit,
+		"ALERT_VALUE="+strconv.FormatFloat(e.Value, 'f', -1, 64),
+		"ALERT_HOSTNAME="+e.Hostname,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
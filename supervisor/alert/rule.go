@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/supervisor/history"
+)
+
+// comparison operators.
+var operators = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+}
+
+// threshold suffixes. Memory sizes are powers of 1024.
+var suffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"%", 1},
+}
+
+// Rule is a threshold on a metric of units matching a glob pattern. A rule fires when the
+// threshold is crossed by every sample for at least For.
+type Rule struct {
+	Name      string
+	Unit      string
+	Metric    string
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseRule parses a rule expression:
+//
+//	<unit> <metric> <op> <threshold>[%|KB|MB|GB] [for <duration>]
+//
+// e.g. "dcos-mesos-slave.service cpu_total > 150% for 30s" or "systemd-journald.service memory_rss > 500MB".
+// Host wide samples are matched by the unit name host.
+func ParseRule(expr string) (*Rule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, fmt.Errorf("Invalid alert rule %q, expected \"<unit> <metric> <op> <threshold> [for <duration>]\"", expr)
+	}
+
+	r := &Rule{
+		Name:   strings.Join(fields, " "),
+		Unit:   fields[0],
+		Metric: fields[1],
+		Op:     fields[2],
+	}
+
+	if _, err := path.Match(r.Unit, ""); err != nil {
+		return nil, fmt.Errorf("Invalid alert rule %q: bad unit pattern: %s", expr, err)
+	}
+
+	if _, err := history.Value(&backend.BigQuerySchema{}, r.Metric); err != nil {
+		return nil, fmt.Errorf("Invalid alert rule %q: %s", expr, err)
+	}
+
+	if _, ok := operators[r.Op]; !ok {
+		return nil, fmt.Errorf("Invalid alert rule %q: unknown operator %s", expr, r.Op)
+	}
+
+	threshold, err := parseThreshold(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid alert rule %q: %s", expr, err)
+	}
+	r.Threshold = threshold
+
+	if len(fields) == 6 {
+		if fields[4] != "for" {
+			return nil, fmt.Errorf("Invalid alert rule %q: expected for, got %s", expr, fields[4])
+		}
+
+		d, err := time.ParseDuration(fields[5])
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("Invalid alert rule %q: bad duration %s", expr, fields[5])
+		}
+		r.For = d
+	}
+	return r, nil
+}
+
+func parseThreshold(s string) (float64, error) {
+	multiplier := 1.0
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(strings.ToUpper(s), suffix.suffix) {
+			s = s[:len(s)-len(suffix.suffix)]
+			multiplier = suffix.multiplier
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad threshold %s", s)
+	}
+	return v * multiplier, nil
+}
+
+// Match returns true if the rule applies to a unit.
+func (r *Rule) Match(unit string) bool {
+	ok, _ := path.Match(r.Unit, unit)
+	return ok
+}
+
+// Crossed returns true if a value crosses the threshold.
+func (r *Rule) Crossed(value float64) bool {
+	return operators[r.Op](value, r.Threshold)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/mesosphere/performance/supervisor/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// authenticate requires a bearer token matching token. An empty token disables authentication.
+func authenticate(next http.Handler, token string) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			http.Error(w, "Invalid bearer token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticateRPC requires a bearer token in the authorization metadata of a gRPC call.
+// An empty token disables authentication.
+func authenticateRPC(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromContext(ctx)
+	values := md["authorization"]
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return grpc.Errorf(codes.Unauthenticated, "Missing bearer token")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(values[0], "Bearer ")), []byte(token)) != 1 {
+		return grpc.Errorf(codes.PermissionDenied, "Invalid bearer token")
+	}
+	return nil
+}
+
+// newTLSConfig returns a server TLS config or nil if TLS is disabled. Client certificates are
+// required and verified if a client CA is configured.
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.FlagTLSCert == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.FlagTLSCert, cfg.FlagTLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load TLS certificate: %s", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.FlagTLSClientCA != "" {
+		pem, err := ioutil.ReadFile(cfg.FlagTLSClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read TLS client CA: %s", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No certificates found in TLS client CA")
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mesosphere/performance/supervisor/backend"
+)
+
+// maxEventsBody is a max size of an /incoming request body.
+const maxEventsBody = 10 << 20
+
+type ingestResponse struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// event accepts a single event, a JSON array of events or NDJSON. Valid events are queued
+// for upload and 202 is returned with accepted and rejected counts. Invalid events are rejected
+// individually, 400 is returned only if nothing was accepted. If the queue is full events are
+// rejected and 503 is returned.
+func event(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+
+	raw, err := decodeEvents(http.MaxBytesReader(w, r.Body, maxEventsBody))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bad request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(raw) == 0 {
+		http.Error(w, "Bad request: no events", http.StatusBadRequest)
+		return
+	}
+
+	resp := &ingestResponse{}
+	full := false
+	for i, msg := range raw {
+		e, err := parseEvent(msg)
+		if err != nil {
+			resp.Rejected++
+			resp.Errors = append(resp.Errors, fmt.Sprintf("event %d: %s", i, err))
+			continue
+		}
+
+		select {
+		case job.events <- e:
+			resp.Accepted++
+		default:
+			full = true
+			resp.Rejected++
+			resp.Errors = append(resp.Errors, fmt.Sprintf("event %d: events queue is full", i))
+		}
+	}
+
+	job.stats.RejectEvents(resp.Rejected)
+
+	code := http.StatusAccepted
+	if resp.Accepted == 0 {
+		code = http.StatusBadRequest
+		if full {
+			w.Header().Set("Retry-After", "1")
+			code = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, code, resp)
+}
+
+// decodeEvents splits a body into raw JSON events. A body is either a JSON array or
+// a sequence of JSON objects, e.g. NDJSON or a single object.
+func decodeEvents(body io.Reader) ([]json.RawMessage, error) {
+	reader := bufio.NewReader(body)
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(reader)
+	if first == '[' {
+		events := []json.RawMessage{}
+		if err := decoder.Decode(&events); err != nil {
+			return nil, err
+		}
+		return events, nil
+	}
+
+	events := []json.RawMessage{}
+	for {
+		var msg json.RawMessage
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %s", len(events), err)
+		}
+		events = append(events, msg)
+	}
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, reader.UnreadByte()
+	}
+}
+
+// parseEvent decodes and validates a single event. A client timestamp is preserved.
+func parseEvent(msg json.RawMessage) (*backend.EventSchema, error) {
+	e := &backend.EventSchema{}
+	if err := json.Unmarshal(msg, e); err != nil {
+		return nil, err
+	}
+
+	if err := prepareEvent(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// prepareEvent validates a decoded event and sets a timestamp if the client did not.
+func prepareEvent(e *backend.EventSchema) error {
+	// legacy events encode all fields in the name
+	if err := e.ParseName(); err != nil {
+		return err
+	}
+
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/supervisor/config"
+	"github.com/mesosphere/performance/supervisor/rpc"
+	"github.com/mesosphere/performance/supervisor/run"
+	"github.com/mesosphere/performance/supervisor/stream"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+)
+
+// maxStreamErrors is a max number of rejected event errors returned to a PushEvents client.
+// Rejections are still counted after the limit.
+const maxStreamErrors = 100
+
+// rpcAuthenticated lists methods which require a bearer token if configured, the same
+// as mutating HTTP endpoints.
+var rpcAuthenticated = map[string]bool{
+	"/" + rpc.ServiceName + "/PushEvents": true,
+	"/" + rpc.ServiceName + "/StartRun":   true,
+	"/" + rpc.ServiceName + "/StopRun":    true,
+}
+
+// listenRPC returns a listener for cfg.FlagGRPCBind or nil if the gRPC server is disabled.
+func listenRPC(cfg *config.Config) (net.Listener, error) {
+	if cfg.FlagGRPCBind == "" {
+		return nil, nil
+	}
+	return net.Listen("tcp", cfg.FlagGRPCBind)
+}
+
+// newRPCServer returns a gRPC server. The server shares TLS and token settings with the web server.
+func newRPCServer(job *Job, tlsConfig *tls.Config) *grpc.Server {
+	cfg := job.cfg.Get()
+
+	token := cfg.FlagAuthToken
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if rpcAuthenticated[info.FullMethod] {
+				if err := authenticateRPC(ctx, token); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if rpcAuthenticated[info.FullMethod] {
+				if err := authenticateRPC(ss.Context(), token); err != nil {
+					return err
+				}
+			}
+			return handler(srv, ss)
+		}),
+	}
+
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		logrus.Infof("Start TLS gRPC server %s", cfg.FlagGRPCBind)
+	} else {
+		logrus.Infof("Start gRPC server %s", cfg.FlagGRPCBind)
+	}
+
+	server := grpc.NewServer(options...)
+	rpc.RegisterSupervisorServer(server, &rpcServer{job: job})
+	return server
+}
+
+// rpcServer implements rpc.SupervisorServer.
+type rpcServer struct {
+	job *Job
+}
+
+// PushEvents queues events received on a stream. Unlike /incoming, a full queue blocks the
+// stream instead of rejecting events, so that fast producers are slowed down by flow control.
+func (s *rpcServer) PushEvents(es rpc.Supervisor_PushEventsServer) error {
+	ctx := es.Context()
+	resp := &rpc.PushEventsResponse{}
+
+	received := make(chan *backend.EventSchema)
+	recvErr := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// Recv blocks, it is called from a separate goroutine to stop the stream on shutdown.
+	go func() {
+		for {
+			m, err := es.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+
+			select {
+			case received <- m.Schema():
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	for i := 0; ; i++ {
+		select {
+		case <-s.job.stopping:
+			return grpc.Errorf(codes.Unavailable, "Supervisor is shutting down")
+
+		case err := <-recvErr:
+			if err == io.EOF {
+				return es.SendAndClose(resp)
+			}
+			return err
+
+		case e := <-received:
+			if err := prepareEvent(e); err != nil {
+				resp.Rejected++
+				s.job.stats.RejectEvents(1)
+				if len(resp.Errors) < maxStreamErrors {
+					resp.Errors = append(resp.Errors, fmt.Sprintf("event %d: %s", i, err))
+				}
+				continue
+			}
+
+			select {
+			case s.job.events <- e:
+				resp.Accepted++
+			case <-s.job.stopping:
+				return grpc.Errorf(codes.Unavailable, "Supervisor is shutting down")
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// StreamSamples sends samples until the client cancels the stream or the supervisor stops.
+func (s *rpcServer) StreamSamples(req *rpc.SamplesRequest, ss rpc.Supervisor_StreamSamplesServer) error {
+	sub := s.job.broker.Subscribe(stream.Filter{Unit: req.Unit, Type: stream.TypeSample})
+	defer s.job.broker.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ss.Context().Done():
+			return nil
+
+		case <-s.job.stopping:
+			return nil
+
+		case m := <-sub.C:
+			if err := ss.Send(rpc.NewSample(m.Data.(*backend.BigQuerySchema))); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *rpcServer) StartRun(ctx context.Context, req *rpc.StartRunRequest) (*rpc.Run, error) {
+	started, err := s.job.runs.Start(req.Description, req.Tags)
+	if err != nil {
+		return nil, grpc.Errorf(runErrorRPCCode(err), "%s", err)
+	}
+	return rpc.NewRun(started), nil
+}
+
+func (s *rpcServer) GetRun(ctx context.Context, req *rpc.RunRequest) (*rpc.Run, error) {
+	found, err := s.job.runs.Get(req.Id)
+	if err != nil {
+		return nil, grpc.Errorf(runErrorRPCCode(err), "%s", err)
+	}
+	return rpc.NewRun(found), nil
+}
+
+func (s *rpcServer) StopRun(ctx context.Context, req *rpc.RunRequest) (*rpc.Run, error) {
+	stopped, err := s.job.runs.Stop(req.Id)
+	if err != nil {
+		return nil, grpc.Errorf(runErrorRPCCode(err), "%s", err)
+	}
+	return rpc.NewRun(stopped), nil
+}
+
+func runErrorRPCCode(err error) codes.Code {
+	switch err {
+	case run.ErrNotFound:
+		return codes.NotFound
+	case run.ErrActive, run.ErrStopped:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
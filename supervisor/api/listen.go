@@ -0,0 +1,75 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/config"
+)
+
+// newListeners returns a TCP listener for cfg.FlagWebServerBind and a unix socket listener for
+// cfg.FlagSocket, each if configured. TLS applies to the TCP listener only, the socket is
+// protected by its file mode.
+func newListeners(cfg *config.Config, tlsConfig *tls.Config) ([]net.Listener, error) {
+	listeners := []net.Listener{}
+
+	if cfg.FlagWebServerBind != "" {
+		l, err := net.Listen("tcp", cfg.FlagWebServerBind)
+		if err != nil {
+			return nil, err
+		}
+
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+			logrus.Infof("Start TLS web server %s", cfg.FlagWebServerBind)
+		} else {
+			logrus.Infof("Start web server %s", cfg.FlagWebServerBind)
+		}
+		listeners = append(listeners, l)
+	}
+
+	if cfg.FlagSocket != "" {
+		l, err := listenUnix(cfg.FlagSocket, cfg.SocketMode)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		logrus.Infof("Start web server on unix socket %s", cfg.FlagSocket)
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on a unix socket, replacing a stale socket file left by a previous run.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("Cannot listen on %s: file exists and is not a socket", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
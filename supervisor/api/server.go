@@ -2,28 +2,54 @@ package api
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
+	"github.com/mesosphere/performance/supervisor/alert"
 	"github.com/mesosphere/performance/supervisor/backend"
 	"github.com/mesosphere/performance/supervisor/config"
+	"github.com/mesosphere/performance/supervisor/history"
+	"github.com/mesosphere/performance/supervisor/run"
+	"github.com/mesosphere/performance/supervisor/stream"
+	"github.com/mesosphere/performance/supervisor/telemetry"
 	"github.com/mesosphere/performance/supervisor/watch"
+	"google.golang.org/grpc"
 )
 
 type key int
 
 const requestIDKey key = 0
 
+const (
+	// streamBuffer is a number of messages buffered for a slow stream subscriber.
+	streamBuffer = 1000
+
+	// streamHeartbeat is an interval to send keep alive comments to stream subscribers.
+	streamHeartbeat = 15 * time.Second
+)
+
 type Job struct {
 	sync.Mutex
 
 	cancel   context.CancelFunc
+	cfg      *config.Dynamic
 	backends []backend.Backend
-	events   chan *backend.BigQuerySchema
+	events   chan *backend.EventSchema
+	stats    *telemetry.Stats
+	runs     *run.Registry
+	recent   *history.History
+	broker   *stream.Broker
+	rpc      *grpc.Server
+	done     chan struct{}
+	stopping chan struct{}
 }
 
 func newContextWithJob(ctx context.Context, job *Job, req *http.Request) context.Context {
@@ -41,43 +67,458 @@ func middleware(next http.Handler, job *Job) http.Handler {
 	})
 }
 
-// StartWebServer starts a gorilla mux web server.
-func StartWebServer(cfg *config.Config) error {
-	ctx, cancel := context.WithCancel(context.Background())
+// StartWebServer starts a gorilla mux web server. It blocks until ctx is canceled, then
+// gracefully stops the server and the watcher and flushes buffered rows to the backends.
+func StartWebServer(ctx context.Context, dyn *config.Dynamic) error {
+	cfg := dyn.Get()
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	listeners, err := newListeners(cfg, tlsConfig)
+	if err != nil {
+		return err
+	}
 
-	bq, err := backend.NewFlatBigQuery(ctx, cfg.FlagProjectID, cfg.FlagDataSet, cfg.FlagTableName)
+	rpcListener, err := listenRPC(cfg)
 	if err != nil {
+		closeListeners(listeners)
 		return err
 	}
 
-	if err := bq.CreateTable(ctx); err != nil {
-		logrus.Warning(err)
+	// backends outlive the watcher context, they are used to flush rows on shutdown.
+	backends, err := newBackends(context.Background(), cfg)
+	if err != nil {
+		closeListeners(listeners)
+		if rpcListener != nil {
+			rpcListener.Close()
+		}
+		return err
 	}
 
+	stats := telemetry.NewStats()
+	watchCtx, cancel := context.WithCancel(context.Background())
 	job := &Job{
 		cancel:   cancel,
-		backends: []backend.Backend{bq},
-		events:   make(chan *backend.BigQuerySchema),
+		cfg:      dyn,
+		backends: backends,
+		events:   make(chan *backend.EventSchema, cfg.FlagEventsBuffer),
+		stats:    stats,
+		runs:     run.NewRegistry(),
+		recent:   history.NewHistory(cfg.SamplesRetention),
+		broker:   stream.NewBroker(streamBuffer),
+		done:     make(chan struct{}),
+		stopping: make(chan struct{}),
 	}
 
-	go watch.StartWatcher(ctx, cfg, job.backends, job.events)
+	alerts, err := newAlerts(cfg, job)
+	if err != nil {
+		cancel()
+		closeListeners(listeners)
+		if rpcListener != nil {
+			rpcListener.Close()
+		}
+		return err
+	}
+
+	go func() {
+		watch.StartWatcher(watchCtx, dyn, job.backends, job.events, job.stats, job.runs, job.recent, job.broker, alerts)
+		close(job.done)
+	}()
+
+	// mutating endpoints require a bearer token if configured
+	token := cfg.FlagAuthToken
 
 	router := mux.NewRouter()
-	router.Path("/incoming").Handler(middleware(http.HandlerFunc(event), job)).Methods("POST")
+	router.Path("/incoming").Handler(authenticate(middleware(http.HandlerFunc(event), job), token)).Methods("POST")
+	router.Path("/status").Handler(middleware(http.HandlerFunc(status), job)).Methods("GET")
+	router.Path("/healthz").Handler(middleware(http.HandlerFunc(healthz), job)).Methods("GET")
+	router.Path("/readyz").Handler(middleware(http.HandlerFunc(readyz), job)).Methods("GET")
+	router.Path("/samples").Handler(middleware(http.HandlerFunc(samples), job)).Methods("GET")
+	router.Path("/config").Handler(middleware(http.HandlerFunc(getConfig), job)).Methods("GET")
+	router.Path("/config").Handler(authenticate(middleware(http.HandlerFunc(putConfig), job), token)).Methods("PUT")
+	router.Path("/stream").Handler(middleware(http.HandlerFunc(streamHandler), job)).Methods("GET")
+	router.Path("/runs").Handler(authenticate(middleware(http.HandlerFunc(startRun), job), token)).Methods("POST")
+	router.Path("/runs/{id}").Handler(middleware(http.HandlerFunc(getRun), job)).Methods("GET")
+	router.Path("/runs/{id}/stop").Handler(authenticate(middleware(http.HandlerFunc(stopRun), job), token)).Methods("POST")
+
+	server := &http.Server{
+		Handler: router,
+	}
+
+	// streams never end on their own, they must be closed for Shutdown to return.
+	server.RegisterOnShutdown(func() {
+		close(job.stopping)
+	})
+
+	errChan := make(chan error, len(listeners)+1)
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errChan <- server.Serve(l)
+		}(l)
+	}
+
+	if rpcListener != nil {
+		job.rpc = newRPCServer(job, tlsConfig)
+		go func() {
+			errChan <- job.rpc.Serve(rpcListener)
+		}()
+	}
+
+	select {
+	case err := <-errChan:
+		job.shutdown(server)
+		return err
+	case <-ctx.Done():
+	}
+
+	return job.shutdown(server)
+}
+
+// shutdown stops the web server, cancels the watcher and waits for buffered rows to be flushed
+// within cfg.ShutdownTimeout.
+func (j *Job) shutdown(server *http.Server) error {
+	timeout := j.cfg.Get().ShutdownTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if server != nil {
+		logrus.Info("Shutting down web server")
+		if err := server.Shutdown(ctx); err != nil {
+			logrus.Errorf("Error shutting down web server: %s", err)
+		}
+	}
+
+	if j.rpc != nil {
+		logrus.Info("Shutting down gRPC server")
+		stopped := make(chan struct{})
+		go func() {
+			j.rpc.GracefulStop()
+			close(stopped)
+		}()
 
-	logrus.Infof("Start web server %s", cfg.FlagWebServerBind)
-	return http.ListenAndServe(cfg.FlagWebServerBind, router)
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			logrus.Error("Timed out waiting for gRPC clients, closing connections")
+			j.rpc.Stop()
+		}
+	}
+
+	j.cancel()
+
+	select {
+	case <-j.done:
+		logrus.Info("Supervisor stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Timed out after %s waiting for buffered rows to be flushed", timeout)
+	}
+}
+
+func newBackends(ctx context.Context, cfg *config.Config) ([]backend.Backend, error) {
+	backends := []backend.Backend{}
+
+	if cfg.FlagProjectID != "" {
+		bq, err := backend.NewFlatBigQuery(ctx, cfg.FlagProjectID, cfg.FlagDataSet, cfg.FlagTableName, cfg.FlagEventsTableName, cfg.FlagRunsTableName)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := bq.CreateTable(ctx); err != nil {
+			logrus.Warning(err)
+		}
+		backends = append(backends, bq)
+	}
+
+	if cfg.FlagWebhookURL != "" {
+		options := []backend.WebhookOption{
+			backend.WebhookOptionFormat(cfg.FlagWebhookFormat),
+			backend.WebhookOptionTimeout(cfg.WebhookTimeout),
+		}
+		if cfg.WebhookTemplate != "" {
+			options = append(options, backend.WebhookOptionTemplate(cfg.WebhookTemplate))
+		}
+		for key, value := range cfg.FlagWebhookHeaders {
+			options = append(options, backend.WebhookOptionHeader(key, value))
+		}
+		if cfg.FlagWebhookUser != "" {
+			options = append(options, backend.WebhookOptionBasicAuth(cfg.FlagWebhookUser, cfg.FlagWebhookPassword))
+		}
+		if cfg.FlagWebhookToken != "" {
+			options = append(options, backend.WebhookOptionBearerToken(cfg.FlagWebhookToken))
+		}
+
+		webhook, err := backend.NewWebhook(cfg.FlagWebhookURL, options...)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, webhook)
+	}
+
+	for i, b := range backends {
+		backends[i] = backend.NewRetryBackend(b, cfg.FlagUploadRetries, cfg.UploadRetryWait)
+	}
+	return backends, nil
+}
+
+// newAlerts returns an alert engine which queues alert events the same way as incoming events.
+func newAlerts(cfg *config.Config, job *Job) (*alert.Engine, error) {
+	notifiers := []alert.Notifier{}
+	if cfg.FlagAlertWebhook != "" {
+		n, err := alert.NewWebhookNotifier(cfg.FlagAlertWebhook)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	if cfg.FlagAlertCommand != "" {
+		n, err := alert.NewCommandNotifier(cfg.FlagAlertCommand)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	emit := func(e *backend.EventSchema) {
+		select {
+		case job.events <- e:
+		default:
+			logrus.Errorf("Events queue is full, dropping alert event %s %s", e.Test, e.Action)
+			job.stats.RejectEvents(1)
+		}
+	}
+	interval := func() time.Duration {
+		cfg := job.cfg.Get()
+		return cfg.Wait + cfg.CPUUsageInterval
+	}
+	return alert.NewEngine(cfg.AlertRules, interval, emit, notifiers...), nil
 }
 
 // handlers
-func event(w http.ResponseWriter, r *http.Request) {
+func status(w http.ResponseWriter, r *http.Request) {
 	job := requestJobFromContext(r.Context())
-	e := &backend.BigQuerySchema{}
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(e); err != nil {
+	writeJSON(w, http.StatusOK, job.stats.Status())
+}
+
+func healthz(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	if err := job.stats.Healthy(job.cfg.Get().UnhealthyAfter); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+func readyz(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	if err := job.stats.Ready(job.cfg.Get().UnhealthyAfter); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+type runRequest struct {
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags"`
+}
+
+func startRun(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	req := &runRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	started, err := job.runs.Start(req.Description, req.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), runErrorCode(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, started)
+}
+
+func getRun(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	found, err := job.runs.Get(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), runErrorCode(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+func stopRun(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	stopped, err := job.runs.Stop(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), runErrorCode(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, stopped)
+}
+
+func runErrorCode(err error) int {
+	switch err {
+	case run.ErrNotFound:
+		return http.StatusNotFound
+	case run.ErrActive, run.ErrStopped:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// samples returns recent samples. Query parameters:
+//
+//	unit   - systemd unit name, all units if empty.
+//	metric - one of cpu_user, cpu_system, cpu_total, memory_rss, all metrics if empty.
+//	since  - duration, e.g. 5m. Defaults to the samples retention.
+//	step   - duration to average samples over, no downsampling if empty.
+//	format - json or csv. Defaults to json.
+func samples(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	params := r.URL.Query()
+
+	q := history.Query{
+		Unit:   params.Get("unit"),
+		Metric: params.Get("metric"),
+	}
+
+	if since := params.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid since %q", since), http.StatusBadRequest)
+			return
+		}
+		q.Since = time.Now().Add(-d)
+	}
+
+	if step := params.Get("step"); step != "" {
+		d, err := time.ParseDuration(step)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid step %q", step), http.StatusBadRequest)
+			return
+		}
+		q.Step = d
+	}
+
+	points, err := job.recent.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch params.Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, points)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"unit", "timestamp", "metric", "value"})
+		for _, p := range points {
+			writer.Write([]string{p.Unit, p.Timestamp.Format(time.RFC3339Nano), p.Metric, strconv.FormatFloat(p.Value, 'f', -1, 64)})
+		}
+		writer.Flush()
+	default:
+		http.Error(w, fmt.Sprintf("Invalid format %q", params.Get("format")), http.StatusBadRequest)
+	}
+}
+
+// streamHandler pushes samples and events as server-sent events. Query parameters:
+//
+//	unit - systemd unit name, all units if empty.
+//	type - sample or event, all types if empty.
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter := stream.Filter{
+		Unit: r.URL.Query().Get("unit"),
+		Type: r.URL.Query().Get("type"),
+	}
+
+	switch filter.Type {
+	case "", stream.TypeSample, stream.TypeEvent:
+	default:
+		http.Error(w, fmt.Sprintf("Invalid type %q", filter.Type), http.StatusBadRequest)
+		return
+	}
+
+	sub := job.broker.Subscribe(filter)
+	defer job.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-job.stopping:
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": dropped %d\n\n", sub.Dropped())
+			flusher.Flush()
+
+		case m := <-sub.C:
+			data, err := json.Marshal(m.Data)
+			if err != nil {
+				logrus.Errorf("Error encoding stream message: %s", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func getConfig(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	writeJSON(w, http.StatusOK, job.cfg.Get().Reloadable())
+}
+
+// putConfig replaces reloadable settings. Omitted fields keep their current values.
+func putConfig(w http.ResponseWriter, r *http.Request) {
+	job := requestJobFromContext(r.Context())
+	reloadable := job.cfg.Get().Reloadable()
+	if err := json.NewDecoder(r.Body).Decode(&reloadable); err != nil {
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
-	e.Timestamp = time.Now()
-	job.events <- e
+
+	updated, err := job.cfg.Reload(reloadable)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated.Reloadable())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Errorf("Error encoding response: %s", err)
+	}
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"cloud.google.com/go/bigquery"
+	"github.com/Sirupsen/logrus"
 )
 
 // Backend describes the storage interface to store results from experiment.
@@ -24,6 +26,8 @@ func NewBigQueryRow() *BigQueryRow {
 }
 
 type BigQueryRow struct {
+	// Kind of a row, either KindSample or KindEvent.
+	Kind string
 	Data map[string]bigquery.Value
 }
 
@@ -32,10 +36,11 @@ func (b *BigQueryRow) Save() (map[string]bigquery.Value, string, error) {
 }
 
 // NewFlatBigQuery returns a new instance of FlatBigQuery. A backend type to upload results
-// to google BigQuery.
-func NewFlatBigQuery(ctx context.Context, projectID, dataset, tableName string) (*FlatBigQuery, error) {
-	if projectID == "" || dataset == "" || tableName == "" {
-		return nil, errors.New("projectID, dataset and tableName cannot be empty")
+// to google BigQuery. Samples are stored in tableName, events are stored in eventsTableName
+// and runs are stored in runsTableName.
+func NewFlatBigQuery(ctx context.Context, projectID, dataset, tableName, eventsTableName, runsTableName string) (*FlatBigQuery, error) {
+	if projectID == "" || dataset == "" || tableName == "" || eventsTableName == "" || runsTableName == "" {
+		return nil, errors.New("projectID, dataset, tableName, eventsTableName and runsTableName cannot be empty")
 	}
 
 	if ctx == nil {
@@ -48,28 +53,37 @@ func NewFlatBigQuery(ctx context.Context, projectID, dataset, tableName string)
 	}
 
 	return &FlatBigQuery{
-		ProjectID: projectID,
-		Dataset:   dataset,
-		TableName: tableName,
-
-		client:   bqClient,
-		uploader: bqClient.Dataset(dataset).Table(tableName).Uploader(),
+		ProjectID:       projectID,
+		Dataset:         dataset,
+		TableName:       tableName,
+		EventsTableName: eventsTableName,
+		RunsTableName:   runsTableName,
+
+		client: bqClient,
+		uploaders: map[string]*bigquery.Uploader{
+			KindSample: bqClient.Dataset(dataset).Table(tableName).Uploader(),
+			KindEvent:  bqClient.Dataset(dataset).Table(eventsTableName).Uploader(),
+			KindRun:    bqClient.Dataset(dataset).Table(runsTableName).Uploader(),
+		},
 	}, nil
 }
 
-// FlatBigQuery is a single table uploader
+// FlatBigQuery is a single table per row kind uploader
 type FlatBigQuery struct {
-	ProjectID string
-	Dataset   string
-	TableName string
-
-	client   *bigquery.Client
-	uploader *bigquery.Uploader
+	ProjectID       string
+	Dataset         string
+	TableName       string
+	EventsTableName string
+	RunsTableName   string
+
+	client    *bigquery.Client
+	uploaders map[string]*bigquery.Uploader
 }
 
 // ID returns a backend name.
 func (f *FlatBigQuery) ID() string {
-	return fmt.Sprintf("Flat BigQuery. ProjectID: %s, Dataset: %s, TableName: %s", f.ProjectID, f.Dataset, f.TableName)
+	return fmt.Sprintf("Flat BigQuery. ProjectID: %s, Dataset: %s, TableName: %s, EventsTableName: %s, RunsTableName: %s",
+		f.ProjectID, f.Dataset, f.TableName, f.EventsTableName, f.RunsTableName)
 }
 
 func (t *FlatBigQuery) Put(ctx context.Context, item interface{}) error {
@@ -82,18 +96,98 @@ func (t *FlatBigQuery) Put(ctx context.Context, item interface{}) error {
 		return errors.New("Item must be a list of references to BigQueryRow onject")
 	}
 
-	return t.uploader.Put(ctx, rows)
+	byKind := map[string][]*BigQueryRow{}
+	for _, row := range rows {
+		byKind[row.Kind] = append(byKind[row.Kind], row)
+	}
+
+	for kind, kindRows := range byKind {
+		uploader, ok := t.uploaders[kind]
+		if !ok {
+			return fmt.Errorf("Unknown row kind %q", kind)
+		}
+
+		if err := uploader.Put(ctx, kindRows); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// CreateTable creates samples, events and runs tables. Columns added to the schemas since an
+// existing table was created are appended to it, BigQuery allows adding columns but not removing
+// or changing them.
 func (t *FlatBigQuery) CreateTable(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	schema, err := bigquery.InferSchema(BigQuerySchema{})
+	tables := []struct {
+		name   string
+		schema interface{}
+	}{
+		{t.TableName, BigQuerySchema{}},
+		{t.EventsTableName, EventSchema{}},
+		{t.RunsTableName, RunSchema{}},
+	}
+
+	// tables are created independently, an already existing samples table must not
+	// prevent the other tables from being created.
+	errs := []string{}
+	for _, table := range tables {
+		schema, err := bigquery.InferSchema(table.schema)
+		if err != nil {
+			return err
+		}
+
+		createErr := t.client.Dataset(t.Dataset).Table(table.name).Create(ctx, schema)
+		if createErr == nil {
+			continue
+		}
+
+		if err := t.updateSchema(ctx, table.name, schema); err != nil {
+			errs = append(errs, fmt.Sprintf("Unable to create table %s: %s; unable to update its schema: %s", table.name, createErr, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// updateSchema appends columns of schema missing in an existing table.
+func (t *FlatBigQuery) updateSchema(ctx context.Context, name string, schema bigquery.Schema) error {
+	table := t.client.Dataset(t.Dataset).Table(name)
+	md, err := table.Metadata(ctx)
 	if err != nil {
 		return err
 	}
 
-	return t.client.Dataset(t.Dataset).Table(t.TableName).Create(ctx, schema)
+	existing := map[string]bool{}
+	for _, field := range md.Schema {
+		existing[strings.ToLower(field.Name)] = true
+	}
+
+	updated := md.Schema
+	added := []string{}
+	for _, field := range schema {
+		if !existing[strings.ToLower(field.Name)] {
+			// columns can only be added as nullable, rows stored before have no value.
+			column := *field
+			column.Required = false
+			updated = append(updated, &column)
+			added = append(added, field.Name)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	if _, err := table.Update(ctx, bigquery.TableMetadataToUpdate{Schema: updated}); err != nil {
+		return err
+	}
+	logrus.Infof("Added columns %s to table %s", strings.Join(added, ", "), name)
+	return nil
 }
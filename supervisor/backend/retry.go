@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// NewRetryBackend wraps a backend and retries a failed Put up to retries times. The wait
+// between attempts starts at wait and doubles after every failure.
+func NewRetryBackend(b Backend, retries int, wait time.Duration) Backend {
+	if retries <= 0 {
+		return b
+	}
+
+	return &RetryBackend{
+		Backend: b,
+		Retries: retries,
+		Wait:    wait,
+	}
+}
+
+// RetryBackend is a backend decorator which retries failed uploads with exponential backoff.
+type RetryBackend struct {
+	Backend
+
+	Retries int
+	Wait    time.Duration
+}
+
+// Put item to the underlying backend, retrying on error.
+func (r *RetryBackend) Put(ctx context.Context, item interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	wait := r.Wait
+	err := r.Backend.Put(ctx, item)
+	for attempt := 1; err != nil && attempt <= r.Retries; attempt++ {
+		logrus.Warnf("Upload to %s failed: %s. Retry %d/%d in %s", r.ID(), err, attempt, r.Retries, wait)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		err = r.Backend.Put(ctx, item)
+		wait *= 2
+	}
+	return err
+}
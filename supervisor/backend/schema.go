@@ -1,11 +1,28 @@
 package backend
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/fatih/structs"
 )
 
+// row kinds, each kind is stored in its own table.
+const (
+	KindSample = "sample"
+	KindEvent  = "event"
+	KindRun    = "run"
+)
+
+// EventNameDelimiter separates parts of a legacy event name:
+//
+//	TEST_SUITE::TEST_NAME::ACTION::VALUE::UNIT
+const EventNameDelimiter = "::"
+
 // google store table schema
 type BigQuerySchema struct {
 	Name            string    `json:"name"`
@@ -13,14 +30,147 @@ type BigQuerySchema struct {
 	UserCPU_Usage   float64
 	SystemCPU_Usage float64
 	TotalCPU_Usage  float64
+	MemoryRSS       int64
 	Hostname        string `json:"hostname"`
 	Instance        string `json:"instance"`
+	RunID           string `json:"run_id"`
+
+	// Tick is a scheduled collection time, WindowStart and WindowEnd is the actual cpu usage
+	// measurement window.
+	Tick        time.Time `json:"tick"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
 }
 
 func (b *BigQuerySchema) ToBigQueryRow() *BigQueryRow {
 	row := NewBigQueryRow()
+	row.Kind = KindSample
 	for key, value := range structs.Map(b) {
 		row.Data[key] = value
 	}
 	return row
 }
+
+// EventLabel is a free-form key/value pair attached to an event.
+type EventLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// EventSchema is a google store table schema for test events.
+type EventSchema struct {
+	Name        string       `json:"name"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Suite       string       `json:"suite"`
+	Test        string       `json:"test"`
+	Action      string       `json:"action"`
+	Value       float64      `json:"value"`
+	Unit        string       `json:"unit"`
+	DurationSec float64      `json:"duration_sec"`
+	Labels      []EventLabel `json:"labels"`
+	RunID       string       `json:"run_id"`
+	Hostname    string       `json:"hostname"`
+	Instance    string       `json:"instance"`
+}
+
+// ParseName fills empty structured fields from a legacy event name
+// TEST_SUITE::TEST_NAME::ACTION::VALUE::UNIT. Missing trailing parts are allowed.
+func (e *EventSchema) ParseName() error {
+	if e.Suite != "" || !strings.Contains(e.Name, EventNameDelimiter) {
+		return nil
+	}
+
+	parts := strings.SplitN(e.Name, EventNameDelimiter, 5)
+	fields := []*string{&e.Suite, &e.Test, &e.Action}
+	for i := 0; i < len(parts) && i < len(fields); i++ {
+		*fields[i] = parts[i]
+	}
+
+	if len(parts) > 3 {
+		value, err := strconv.ParseFloat(parts[3], 64)
+		if err != nil {
+			return fmt.Errorf("Invalid value %q in event name %s", parts[3], e.Name)
+		}
+		e.Value = value
+	}
+
+	if len(parts) > 4 {
+		e.Unit = parts[4]
+	}
+	return nil
+}
+
+// Validate returns an error if required fields are missing or invalid.
+func (e *EventSchema) Validate() error {
+	if e.Name == "" && e.Suite == "" {
+		return errors.New("field name or suite is required")
+	}
+
+	if e.Suite != "" && e.Test == "" {
+		return errors.New("field test is required when suite is set")
+	}
+
+	if e.DurationSec < 0 {
+		return fmt.Errorf("field duration_sec must not be negative, got %f", e.DurationSec)
+	}
+
+	for i, label := range e.Labels {
+		if label.Key == "" {
+			return fmt.Errorf("field labels[%d].key is required", i)
+		}
+	}
+	return nil
+}
+
+// SetLabel adds or replaces a label.
+func (e *EventSchema) SetLabel(key, value string) {
+	for i := range e.Labels {
+		if e.Labels[i].Key == key {
+			e.Labels[i].Value = value
+			return
+		}
+	}
+	e.Labels = append(e.Labels, EventLabel{Key: key, Value: value})
+}
+
+func (e *EventSchema) ToBigQueryRow() *BigQueryRow {
+	row := NewBigQueryRow()
+	row.Kind = KindEvent
+	for key, value := range structs.Map(e) {
+		row.Data[key] = value
+	}
+	row.Data["Labels"] = labelsToValue(e.Labels)
+	return row
+}
+
+// RunSchema is a google store table schema for run lifecycle records. A row is stored
+// when a run starts and when it stops.
+type RunSchema struct {
+	RunID       string       `json:"run_id"`
+	Action      string       `json:"action"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Description string       `json:"description"`
+	Tags        []EventLabel `json:"tags"`
+	Hostname    string       `json:"hostname"`
+}
+
+func (r *RunSchema) ToBigQueryRow() *BigQueryRow {
+	row := NewBigQueryRow()
+	row.Kind = KindRun
+	for key, value := range structs.Map(r) {
+		row.Data[key] = value
+	}
+	row.Data["Tags"] = labelsToValue(r.Tags)
+	return row
+}
+
+func labelsToValue(labels []EventLabel) []bigquery.Value {
+	values := []bigquery.Value{}
+	for _, label := range labels {
+		values = append(values, map[string]bigquery.Value{
+			"Key":   label.Key,
+			"Value": label.Value,
+		})
+	}
+	return values
+}
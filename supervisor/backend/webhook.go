@@ -0,0 +1,204 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// predefined webhook body formats.
+const (
+	WebhookFormatNDJSON = "ndjson"
+	WebhookFormatJSON   = "json"
+	WebhookFormatESBulk = "es-bulk"
+)
+
+var webhookFormats = map[string]struct {
+	contentType string
+	body        string
+}{
+	WebhookFormatNDJSON: {"application/x-ndjson", `{{range .Rows}}{{json .}}` + "\n" + `{{end}}`},
+	WebhookFormatJSON:   {"application/json", `[{{range $i, $row := .Rows}}{{if $i}},{{end}}{{json $row}}{{end}}]`},
+	WebhookFormatESBulk: {"application/x-ndjson", `{{range .Rows}}{"index":{}}` + "\n" + `{{json .}}` + "\n" + `{{end}}`},
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// WebhookPayload is passed to a webhook body template. Every row has a Kind column
+// set to either KindSample or KindEvent.
+type WebhookPayload struct {
+	Rows []map[string]bigquery.Value
+}
+
+// WebhookOption configures a Webhook backend.
+type WebhookOption func(*Webhook) error
+
+// WebhookOptionFormat renders the request body with one of the predefined formats:
+// ndjson, json or es-bulk.
+func WebhookOptionFormat(format string) WebhookOption {
+	return func(w *Webhook) error {
+		f, ok := webhookFormats[format]
+		if !ok {
+			return fmt.Errorf("Unknown webhook format %s", format)
+		}
+		w.header.Set("Content-Type", f.contentType)
+		return w.setTemplate(f.body)
+	}
+}
+
+// WebhookOptionTemplate renders the request body with a custom text/template. The template
+// is executed with WebhookPayload and has a json function available.
+func WebhookOptionTemplate(body string) WebhookOption {
+	return func(w *Webhook) error {
+		return w.setTemplate(body)
+	}
+}
+
+// WebhookOptionHeader adds a header to every request.
+func WebhookOptionHeader(key, value string) WebhookOption {
+	return func(w *Webhook) error {
+		w.header.Set(key, value)
+		return nil
+	}
+}
+
+// WebhookOptionBasicAuth sets basic auth credentials.
+func WebhookOptionBasicAuth(username, password string) WebhookOption {
+	return func(w *Webhook) error {
+		w.username = username
+		w.password = password
+		return nil
+	}
+}
+
+// WebhookOptionBearerToken sets a bearer token to the Authorization header.
+func WebhookOptionBearerToken(token string) WebhookOption {
+	return func(w *Webhook) error {
+		w.header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// WebhookOptionTimeout sets a timeout for a single request.
+func WebhookOptionTimeout(timeout time.Duration) WebhookOption {
+	return func(w *Webhook) error {
+		if timeout <= 0 {
+			return fmt.Errorf("Invalid webhook timeout %s", timeout)
+		}
+		w.client.Timeout = timeout
+		return nil
+	}
+}
+
+// NewWebhook returns a new instance of Webhook. A backend type to POST batches of rows
+// to an arbitrary URL. By default the body is rendered as ndjson.
+func NewWebhook(url string, options ...WebhookOption) (*Webhook, error) {
+	if url == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	w := &Webhook{
+		URL:    url,
+		header: http.Header{},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	options = append([]WebhookOption{WebhookOptionFormat(WebhookFormatNDJSON)}, options...)
+	for _, option := range options {
+		if err := option(w); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Webhook is a backend which POSTs rows rendered from a template to a URL.
+type Webhook struct {
+	URL string
+
+	header   http.Header
+	username string
+	password string
+	tmpl     *template.Template
+	client   *http.Client
+}
+
+func (w *Webhook) setTemplate(body string) error {
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(body)
+	if err != nil {
+		return fmt.Errorf("Invalid webhook template: %s", err)
+	}
+	w.tmpl = tmpl
+	return nil
+}
+
+// ID returns a backend name.
+func (w *Webhook) ID() string {
+	return fmt.Sprintf("Webhook. URL: %s", w.URL)
+}
+
+func (w *Webhook) Put(ctx context.Context, item interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, ok := item.([]*BigQueryRow)
+	if !ok {
+		return errors.New("Item must be a list of references to BigQueryRow onject")
+	}
+
+	payload := WebhookPayload{}
+	for _, row := range rows {
+		data := map[string]bigquery.Value{"Kind": row.Kind}
+		for key, value := range row.Data {
+			data[key] = value
+		}
+		payload.Rows = append(payload.Rows, data)
+	}
+
+	body := &bytes.Buffer{}
+	if err := w.tmpl.Execute(body, payload); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", w.URL, body)
+	if err != nil {
+		return err
+	}
+
+	for key, values := range w.header {
+		req.Header[key] = values
+	}
+
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Got %d response code from %s: %s", resp.StatusCode, w.URL, msg)
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
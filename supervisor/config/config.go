@@ -4,43 +4,177 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/alert"
 )
 
 const supervisor = "supervisor"
 
+// result queue drop policies.
+const (
+	DropOldest = "drop-oldest"
+	DropNewest = "drop-newest"
+)
+
 type Config struct {
+	FlagConfigFile         string
 	FlagVerbose            bool
 	FlagWebServerBind      string
 	FlagWaitBetweenCollect string
+	FlagAlignTicks         bool
 	FlagCPUUsageInterval   string
 	FlagUploadInterval     string
+	FlagUnhealthyAfter     string
+	FlagShutdownTimeout    string
+	FlagSamplesRetention   string
+	FlagIncludeUnits       string
+	FlagExcludeUnits       string
+	FlagWorkers            int
+	FlagResultsQueue       int
+	FlagDropPolicy         string
+
+	// api security
+	FlagTLSCert     string
+	FlagTLSKey      string
+	FlagTLSClientCA string
+	FlagAuthToken   string
+	FlagSocket      string
+	FlagSocketMode  string
+	FlagGRPCBind    string
 
 	// bigquery config
-	FlagProjectID  string
-	FlagDataSet    string
-	FlagTableName  string
-	FlagBufferSize int
+	FlagProjectID       string
+	FlagDataSet         string
+	FlagTableName       string
+	FlagEventsTableName string
+	FlagRunsTableName   string
+	FlagBufferSize      int
+	FlagEventsBuffer    int
+
+	// upload retries, shared by all backends
+	FlagUploadRetries   int
+	FlagUploadRetryWait string
+
+	// webhook config
+	FlagWebhookURL      string
+	FlagWebhookFormat   string
+	FlagWebhookTemplate string
+	FlagWebhookHeaders  headers
+	FlagWebhookUser     string
+	FlagWebhookPassword string
+	FlagWebhookToken    string
+	FlagWebhookTimeout  string
+
+	// alerts config
+	FlagAlertRules   list
+	FlagAlertWebhook string
+	FlagAlertCommand string
 
 	// unexported values
 	Wait             time.Duration
 	CPUUsageInterval time.Duration
 	UploadInterval   time.Duration
+	UploadRetryWait  time.Duration
+	WebhookTimeout   time.Duration
+	WebhookTemplate  string
+	UnhealthyAfter   time.Duration
+	ShutdownTimeout  time.Duration
+	SamplesRetention time.Duration
+	IncludeUnits     []string
+	ExcludeUnits     []string
+	SocketMode       os.FileMode
+	AlertRules       []*alert.Rule
+
+	// flags set on the command line or by environment variables, the config file does not override them.
+	explicit map[string]bool
+}
+
+// list is a repeatable flag of strings.
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, "; ")
+}
+
+func (l *list) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// headers is a repeatable flag of "Key: Value" pairs.
+type headers map[string]string
+
+func (h headers) String() string {
+	pairs := []string{}
+	for key, value := range h {
+		pairs = append(pairs, key+": "+value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (h headers) Set(s string) error {
+	kv := strings.SplitN(s, ":", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("Invalid header %q, expected \"Key: Value\"", s)
+	}
+	h[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	return nil
 }
 
 func (c *Config) setFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.FlagConfigFile, "config", c.FlagConfigFile, "Load settings from a JSON config file.")
 	fs.BoolVar(&c.FlagVerbose, "verbose", c.FlagVerbose, "Print out verbose output.")
-	fs.StringVar(&c.FlagWebServerBind, "bind", c.FlagWebServerBind, "Bind to addr:port.")
+	fs.StringVar(&c.FlagWebServerBind, "bind", c.FlagWebServerBind, "Bind to addr:port. Set empty to listen on a unix socket only.")
+	fs.StringVar(&c.FlagSocket, "socket", c.FlagSocket, "Listen on a unix socket in addition to -bind, e.g. /run/perf-supervisor.sock.")
+	fs.StringVar(&c.FlagSocketMode, "socket-mode", c.FlagSocketMode, "Set unix socket file mode.")
+	fs.StringVar(&c.FlagGRPCBind, "grpc-bind", c.FlagGRPCBind, "Serve the gRPC API on addr:port. Disabled if empty.")
 	fs.StringVar(&c.FlagWaitBetweenCollect, "interval", c.FlagWaitBetweenCollect, "Set metrics collection interval.")
+	fs.BoolVar(&c.FlagAlignTicks, "align-ticks", c.FlagAlignTicks, "Start collections at wall-clock multiples of -interval, e.g. :00, :05 for 5s.")
 	fs.StringVar(&c.FlagCPUUsageInterval, "cpu-interval", c.FlagCPUUsageInterval, "Set cpu usage report interval.")
 	fs.StringVar(&c.FlagUploadInterval, "upload-interval", c.FlagUploadInterval, "Set upload interval.")
+	fs.StringVar(&c.FlagUnhealthyAfter, "unhealthy-after", c.FlagUnhealthyAfter, "Report unhealthy when uploads are failing longer than this.")
+	fs.StringVar(&c.FlagShutdownTimeout, "shutdown-timeout", c.FlagShutdownTimeout, "Set max time to flush buffered rows on shutdown.")
+	fs.StringVar(&c.FlagSamplesRetention, "samples-retention", c.FlagSamplesRetention, "Set how long recent samples are kept in memory.")
+	fs.StringVar(&c.FlagIncludeUnits, "include-units", c.FlagIncludeUnits, "Watch only units matching comma separated glob patterns.")
+	fs.StringVar(&c.FlagExcludeUnits, "exclude-units", c.FlagExcludeUnits, "Do not watch units matching comma separated glob patterns.")
+	fs.StringVar(&c.FlagTLSCert, "tls-cert", c.FlagTLSCert, "Serve the API over TLS with a certificate file.")
+	fs.StringVar(&c.FlagTLSKey, "tls-key", c.FlagTLSKey, "Set TLS certificate key file.")
+	fs.StringVar(&c.FlagTLSClientCA, "tls-client-ca", c.FlagTLSClientCA, "Require client certificates signed by a CA file.")
+	fs.StringVar(&c.FlagAuthToken, "auth-token", c.FlagAuthToken, "Require a bearer token for /incoming, mutating endpoints and gRPC calls.")
+	fs.IntVar(&c.FlagWorkers, "workers", c.FlagWorkers, "Set max units measured concurrently. A round takes -cpu-interval per every -workers units.")
+	fs.IntVar(&c.FlagResultsQueue, "results-queue", c.FlagResultsQueue, "Set a number of measurements queued for processing.")
+	fs.StringVar(&c.FlagDropPolicy, "drop-policy", c.FlagDropPolicy, "Set what is dropped when the results queue is full: drop-oldest or drop-newest.")
 	fs.IntVar(&c.FlagBufferSize, "rows-buffer", c.FlagBufferSize, "Set rows buffer size.")
+	fs.IntVar(&c.FlagEventsBuffer, "events-buffer", c.FlagEventsBuffer, "Set a number of incoming events queued for processing.")
 
-	fs.StringVar(&c.FlagProjectID, "project-id", c.FlagProjectID, "Set bigquery ProjectID.")
+	fs.StringVar(&c.FlagProjectID, "project-id", c.FlagProjectID, "Set bigquery ProjectID. BigQuery backend is disabled if empty.")
 	fs.StringVar(&c.FlagDataSet, "dataset", c.FlagDataSet, "Set bigquery dataset.")
 	fs.StringVar(&c.FlagTableName, "table", c.FlagTableName, "Set bigquery table name.")
+	fs.StringVar(&c.FlagEventsTableName, "events-table", c.FlagEventsTableName, "Set bigquery events table name. Defaults to <table>_events.")
+	fs.StringVar(&c.FlagRunsTableName, "runs-table", c.FlagRunsTableName, "Set bigquery runs table name. Defaults to <table>_runs.")
+
+	fs.IntVar(&c.FlagUploadRetries, "upload-retries", c.FlagUploadRetries, "Set number of retries for a failed upload.")
+	fs.StringVar(&c.FlagUploadRetryWait, "upload-retry-wait", c.FlagUploadRetryWait, "Set initial wait between upload retries.")
+
+	fs.StringVar(&c.FlagWebhookURL, "webhook-url", c.FlagWebhookURL, "POST uploaded rows to a URL.")
+	fs.StringVar(&c.FlagWebhookFormat, "webhook-format", c.FlagWebhookFormat, "Set webhook body format: ndjson, json or es-bulk.")
+	fs.StringVar(&c.FlagWebhookTemplate, "webhook-template", c.FlagWebhookTemplate, "Set a path to a text/template file to render webhook body.")
+	fs.Var(c.FlagWebhookHeaders, "webhook-header", "Add a webhook request header \"Key: Value\". Can be repeated.")
+	fs.StringVar(&c.FlagWebhookUser, "webhook-user", c.FlagWebhookUser, "Set webhook basic auth username.")
+	fs.StringVar(&c.FlagWebhookPassword, "webhook-password", c.FlagWebhookPassword, "Set webhook basic auth password.")
+	fs.StringVar(&c.FlagWebhookToken, "webhook-token", c.FlagWebhookToken, "Set webhook bearer token.")
+	fs.StringVar(&c.FlagWebhookTimeout, "webhook-timeout", c.FlagWebhookTimeout, "Set webhook request timeout.")
+
+	fs.Var(&c.FlagAlertRules, "alert-rule", "Add an alert rule \"<unit> <metric> <op> <threshold> [for <duration>]\", e.g. \"dcos-mesos-slave.service cpu_total > 150% for 30s\". Can be repeated.")
+	fs.StringVar(&c.FlagAlertWebhook, "alert-webhook", c.FlagAlertWebhook, "POST firing and resolved alert events to a URL.")
+	fs.StringVar(&c.FlagAlertCommand, "alert-command", c.FlagAlertCommand, "Run a shell command for firing and resolved alert events.")
 }
 
 func NewConfig(args []string) (c *Config, err error) {
@@ -52,14 +186,28 @@ func NewConfig(args []string) (c *Config, err error) {
 
 	// default values
 	c.FlagWebServerBind = ":9123"
+	c.FlagSocketMode = "0660"
 	c.FlagWaitBetweenCollect = "3s"
 	c.FlagCPUUsageInterval = "2s"
 	c.FlagUploadInterval = "10s"
+	c.FlagUnhealthyAfter = "5m"
+	c.FlagShutdownTimeout = "30s"
+	c.FlagSamplesRetention = "15m"
+	c.FlagWorkers = 64
+	c.FlagResultsQueue = 1000
+	c.FlagDropPolicy = DropOldest
 
-	c.FlagProjectID = "massive-bliss-781"
-	c.FlagDataSet = "dcos_performance2"
-	c.FlagTableName = "mnaboka"
+	c.FlagDataSet = "dcos_performance"
+	c.FlagTableName = "supervisor"
 	c.FlagBufferSize = 1000
+	c.FlagEventsBuffer = 10000
+
+	c.FlagUploadRetries = 3
+	c.FlagUploadRetryWait = "1s"
+
+	c.FlagWebhookFormat = "ndjson"
+	c.FlagWebhookHeaders = headers{}
+	c.FlagWebhookTimeout = "10s"
 
 	flagSet := flag.NewFlagSet(supervisor, flag.ContinueOnError)
 	c.setFlags(flagSet)
@@ -68,29 +216,171 @@ func NewConfig(args []string) (c *Config, err error) {
 		return nil, err
 	}
 
+	// command line flags take precedence over environment variables, which take
+	// precedence over the config file.
+	set := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["config"] {
+		c.FlagConfigFile = os.Getenv(envName("config"))
+	}
+
+	errs := Errors{}
+	if c.FlagConfigFile != "" {
+		errs = append(errs, loadFile(c.FlagConfigFile, flagSet, set)...)
+	}
+	errs = append(errs, loadEnv(flagSet, set)...)
+	c.explicit = set
+
+	if c.FlagEventsTableName == "" {
+		c.FlagEventsTableName = c.FlagTableName + "_events"
+	}
+
+	if c.FlagRunsTableName == "" {
+		c.FlagRunsTableName = c.FlagTableName + "_runs"
+	}
+
 	if c.FlagVerbose {
 		logrus.SetLevel(logrus.DebugLevel)
 		logrus.Debug("Using debug level")
 	}
 
-	c.Wait, err = time.ParseDuration(c.FlagWaitBetweenCollect)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot parse flag interval: %s", err)
+	if err := c.parse(); err != nil {
+		errs = append(errs, err.(Errors)...)
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parse validates flag values and sets parsed values. All invalid values are reported together.
+func (c *Config) parse() error {
+	errs := Errors{}
+
+	c.Wait = errs.duration("interval", c.FlagWaitBetweenCollect, false)
+	c.CPUUsageInterval = errs.duration("cpu-interval", c.FlagCPUUsageInterval, false)
+	c.UploadInterval = errs.duration("upload-interval", c.FlagUploadInterval, false)
+	c.UnhealthyAfter = errs.duration("unhealthy-after", c.FlagUnhealthyAfter, false)
+	c.ShutdownTimeout = errs.duration("shutdown-timeout", c.FlagShutdownTimeout, false)
+	c.SamplesRetention = errs.duration("samples-retention", c.FlagSamplesRetention, false)
+	c.UploadRetryWait = errs.duration("upload-retry-wait", c.FlagUploadRetryWait, true)
+	c.WebhookTimeout = errs.duration("webhook-timeout", c.FlagWebhookTimeout, false)
+
+	c.AlertRules = nil
+	for _, expr := range c.FlagAlertRules {
+		rule, err := alert.ParseRule(expr)
+		if err != nil {
+			errs.add("%s", err)
+			continue
+		}
+		c.AlertRules = append(c.AlertRules, rule)
+	}
+
+	if c.FlagWorkers <= 0 {
+		errs.add("Invalid workers %d", c.FlagWorkers)
+	}
+
+	if c.FlagResultsQueue <= 0 {
+		errs.add("Invalid results-queue %d", c.FlagResultsQueue)
+	}
+
+	if c.FlagDropPolicy != DropOldest && c.FlagDropPolicy != DropNewest {
+		errs.add("Invalid drop-policy %s, expected %s or %s", c.FlagDropPolicy, DropOldest, DropNewest)
+	}
+
+	if c.FlagBufferSize <= 0 {
+		errs.add("Invalid rows-buffer %d", c.FlagBufferSize)
+	}
+
+	if c.FlagEventsBuffer <= 0 {
+		errs.add("Invalid events-buffer %d", c.FlagEventsBuffer)
 	}
 
-	c.CPUUsageInterval, err = time.ParseDuration(c.FlagCPUUsageInterval)
+	if c.FlagUploadRetries < 0 {
+		errs.add("Invalid upload-retries %d", c.FlagUploadRetries)
+	}
+
+	if c.FlagWebServerBind == "" && c.FlagSocket == "" {
+		errs.add("No listener configured, set bind or socket")
+	}
+
+	mode, err := strconv.ParseUint(c.FlagSocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		errs.add("Invalid socket-mode %s", c.FlagSocketMode)
+	}
+	c.SocketMode = os.FileMode(mode)
+
+	if (c.FlagTLSCert == "") != (c.FlagTLSKey == "") {
+		errs.add("Both tls-cert and tls-key must be set")
+	}
+
+	if c.FlagTLSClientCA != "" && c.FlagTLSCert == "" {
+		errs.add("tls-client-ca requires tls-cert and tls-key")
+	}
+
+	if c.FlagProjectID == "" && c.FlagWebhookURL == "" {
+		errs.add("No backend configured, set project-id or webhook-url")
+	}
+
+	if c.FlagWebhookTemplate != "" {
+		body, err := ioutil.ReadFile(c.FlagWebhookTemplate)
+		if err != nil {
+			errs.add("Cannot read webhook template: %s", err)
+		}
+		c.WebhookTemplate = string(body)
+	}
+
+	c.IncludeUnits, err = parsePatterns(c.FlagIncludeUnits)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot parse flag cpu-interval: %s", err)
+		errs.add("Cannot parse flag include-units: %s", err)
 	}
 
-	c.UploadInterval, err = time.ParseDuration(c.FlagUploadInterval)
+	c.ExcludeUnits, err = parsePatterns(c.FlagExcludeUnits)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot parse flag upload-interval: %s", err)
+		errs.add("Cannot parse flag exclude-units: %s", err)
 	}
 
-	if c.Wait <= 0 || c.CPUUsageInterval <= 0 || c.UploadInterval <= 0 {
-		return nil, fmt.Errorf("Invalid Wait interval %s", c.Wait.String())
+	return errs.err()
+}
+
+// parsePatterns splits comma separated glob patterns.
+func parsePatterns(s string) ([]string, error) {
+	patterns := []string{}
+	for _, pattern := range strings.Split(s, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: %s", pattern, err)
+		}
+		patterns = append(patterns, pattern)
 	}
+	return patterns, nil
+}
 
-	return c, nil
+// MatchUnit returns true if a unit should be watched according to include and exclude patterns.
+func (c *Config) MatchUnit(name string) bool {
+	for _, pattern := range c.ExcludeUnits {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	if len(c.IncludeUnits) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.IncludeUnits {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
 }
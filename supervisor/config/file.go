@@ -0,0 +1,157 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix is a prefix of environment variables overriding flags, e.g. SUPERVISOR_UPLOAD_INTERVAL
+// overrides -upload-interval.
+const envPrefix = "SUPERVISOR_"
+
+// Errors is a list of config errors reported together.
+type Errors []string
+
+func (e Errors) Error() string {
+	return strings.Join(e, "; ")
+}
+
+func (e *Errors) add(format string, args ...interface{}) {
+	*e = append(*e, fmt.Sprintf(format, args...))
+}
+
+// duration parses a flag value. Zero is allowed only if allowZero is set, negative values
+// are never allowed.
+func (e *Errors) duration(name, value string, allowZero bool) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.add("Cannot parse flag %s: %s", name, err)
+		return 0
+	}
+
+	if d < 0 || (d == 0 && !allowZero) {
+		e.add("Invalid %s %s", name, d)
+	}
+	return d
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// envName returns an environment variable name for a flag.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// loadEnv sets flags from SUPERVISOR_* environment variables, skipping flags set on the command line.
+// Flags set from the environment are added to set.
+func loadEnv(fs *flag.FlagSet, set map[string]bool) Errors {
+	errs := Errors{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || f.Name == "config" {
+			return
+		}
+
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+
+		if err := fs.Set(f.Name, value); err != nil {
+			errs.add("Invalid environment variable %s: %s", envName(f.Name), err)
+		}
+		set[f.Name] = true
+	})
+	return errs
+}
+
+// loadFile sets flags from a JSON config file, skipping flags set on the command line. Keys
+// are flag names. Nested objects only group settings, e.g.
+//
+//	{"intervals": {"interval": "3s"}, "backends": {"webhook": {"webhook-url": "http://..."}}}
+//
+// Arrays set repeatable flags once per element, other flags get elements joined with commas.
+func loadFile(path string, fs *flag.FlagSet, set map[string]bool) Errors {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Errors{fmt.Sprintf("Cannot read config file: %s", err)}
+	}
+
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal(body, &settings); err != nil {
+		return Errors{fmt.Sprintf("Cannot parse config file %s: %s", path, err)}
+	}
+
+	errs := Errors{}
+	loadSettings(settings, fs, set, &errs)
+	return errs
+}
+
+func loadSettings(settings map[string]interface{}, fs *flag.FlagSet, set map[string]bool, errs *Errors) {
+	keys := []string{}
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := settings[key]
+		if group, ok := value.(map[string]interface{}); ok {
+			loadSettings(group, fs, set, errs)
+			continue
+		}
+
+		f := fs.Lookup(key)
+		if f == nil || key == "config" {
+			errs.add("Unknown setting %s in config file", key)
+			continue
+		}
+
+		if set[key] {
+			continue
+		}
+
+		for _, v := range flagValues(f, value) {
+			if err := fs.Set(key, v); err != nil {
+				errs.add("Invalid setting %s in config file: %s", key, err)
+			}
+		}
+	}
+}
+
+// flagValues converts a JSON value to flag values.
+func flagValues(f *flag.Flag, value interface{}) []string {
+	elements, ok := value.([]interface{})
+	if !ok {
+		return []string{jsonString(value)}
+	}
+
+	values := []string{}
+	for _, v := range elements {
+		values = append(values, jsonString(v))
+	}
+
+	switch f.Value.(type) {
+	case headers, *list:
+		return values
+	}
+	return []string{strings.Join(values, ",")}
+}
+
+func jsonString(v interface{}) string {
+	if n, ok := v.(float64); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
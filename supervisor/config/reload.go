@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Reloadable holds settings which can be changed while the supervisor is running.
+type Reloadable struct {
+	Interval       string   `json:"interval"`
+	AlignTicks     bool     `json:"align_ticks"`
+	CPUInterval    string   `json:"cpu_interval"`
+	UploadInterval string   `json:"upload_interval"`
+	BufferSize     int      `json:"rows_buffer"`
+	IncludeUnits   []string `json:"include_units"`
+	ExcludeUnits   []string `json:"exclude_units"`
+}
+
+// Reloadable returns current values of reloadable settings.
+func (c *Config) Reloadable() Reloadable {
+	return Reloadable{
+		Interval:       c.FlagWaitBetweenCollect,
+		AlignTicks:     c.FlagAlignTicks,
+		CPUInterval:    c.FlagCPUUsageInterval,
+		UploadInterval: c.FlagUploadInterval,
+		BufferSize:     c.FlagBufferSize,
+		IncludeUnits:   c.IncludeUnits,
+		ExcludeUnits:   c.ExcludeUnits,
+	}
+}
+
+// WithReloadable returns a copy of the config with reloadable settings replaced. The copy
+// is validated the same way NewConfig does.
+func (c *Config) WithReloadable(r Reloadable) (*Config, error) {
+	updated := *c
+	updated.FlagWaitBetweenCollect = r.Interval
+	updated.FlagAlignTicks = r.AlignTicks
+	updated.FlagCPUUsageInterval = r.CPUInterval
+	updated.FlagUploadInterval = r.UploadInterval
+	updated.FlagBufferSize = r.BufferSize
+	updated.FlagIncludeUnits = strings.Join(r.IncludeUnits, ",")
+	updated.FlagExcludeUnits = strings.Join(r.ExcludeUnits, ",")
+
+	if err := updated.parse(); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// FileReloadable returns reloadable settings of the config updated from its config file. Settings
+// missing in the file keep their current values, settings set on the command line or by SUPERVISOR_*
+// variables are not overridden by the file. The command line and the environment are not read again.
+func (c *Config) FileReloadable() (Reloadable, error) {
+	if c.FlagConfigFile == "" {
+		return Reloadable{}, errors.New("no config file to reload settings from")
+	}
+
+	updated := *c
+	// repeatable flags are reset so that the file does not append to values of the running config.
+	updated.FlagWebhookHeaders = headers{}
+	updated.FlagAlertRules = nil
+
+	flagSet := flag.NewFlagSet(supervisor, flag.ContinueOnError)
+	updated.setFlags(flagSet)
+
+	errs := loadFile(c.FlagConfigFile, flagSet, c.explicit)
+	if err := updated.parse(); err != nil {
+		errs = append(errs, err.(Errors)...)
+	}
+
+	if err := errs.err(); err != nil {
+		return Reloadable{}, err
+	}
+	return updated.Reloadable(), nil
+}
+
+// NewDynamic returns a new instance of Dynamic.
+func NewDynamic(cfg *Config) *Dynamic {
+	return &Dynamic{
+		cfg:     cfg,
+		changed: make(chan struct{}),
+	}
+}
+
+// Dynamic holds a config which can be replaced at runtime. Readers must call Get for every
+// use instead of keeping a reference to the config, or use Watch to learn when it is replaced.
+type Dynamic struct {
+	sync.RWMutex
+
+	cfg     *Config
+	changed chan struct{}
+}
+
+// Get returns the current config.
+func (d *Dynamic) Get() *Config {
+	d.RLock()
+	defer d.RUnlock()
+	return d.cfg
+}
+
+// Watch returns the current config and a channel which is closed when it is replaced.
+func (d *Dynamic) Watch() (*Config, <-chan struct{}) {
+	d.RLock()
+	defer d.RUnlock()
+	return d.cfg, d.changed
+}
+
+// Reload replaces reloadable settings of the current config. An invalid change is rejected
+// and the current config is kept.
+func (d *Dynamic) Reload(r Reloadable) (*Config, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	updated, err := d.cfg.WithReloadable(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d.cfg = updated
+	close(d.changed)
+	d.changed = make(chan struct{})
+
+	logrus.Infof("Config reloaded: %+v", r)
+	return updated, nil
+}
@@ -0,0 +1,191 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mesosphere/performance/supervisor/backend"
+)
+
+// metric names.
+const (
+	MetricCPUUser   = "cpu_user"
+	MetricCPUSystem = "cpu_system"
+	MetricCPUTotal  = "cpu_total"
+	MetricMemoryRSS = "memory_rss"
+)
+
+var metrics = map[string]func(*backend.BigQuerySchema) float64{
+	MetricCPUUser:   func(s *backend.BigQuerySchema) float64 { return s.UserCPU_Usage },
+	MetricCPUSystem: func(s *backend.BigQuerySchema) float64 { return s.SystemCPU_Usage },
+	MetricCPUTotal:  func(s *backend.BigQuerySchema) float64 { return s.TotalCPU_Usage },
+	MetricMemoryRSS: func(s *backend.BigQuerySchema) float64 { return float64(s.MemoryRSS) },
+}
+
+// Value returns a metric value of a sample.
+func Value(s *backend.BigQuerySchema, metric string) (float64, error) {
+	value, ok := metrics[metric]
+	if !ok {
+		return 0, fmt.Errorf("Unknown metric %s. Available metrics: %s", metric, strings.Join(Metrics(), ", "))
+	}
+	return value(s), nil
+}
+
+// Point is a single metric value of a unit.
+type Point struct {
+	Unit      string    `json:"unit"`
+	Timestamp time.Time `json:"timestamp"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+}
+
+// Query selects points from History. Empty Unit or Metric select all units or metrics,
+// a non zero Step averages points into Step wide buckets.
+type Query struct {
+	Unit   string
+	Metric string
+	Since  time.Time
+	Step   time.Duration
+}
+
+// NewHistory returns a new instance of History which keeps samples for retention.
+func NewHistory(retention time.Duration) *History {
+	return &History{
+		retention: retention,
+		units:     map[string][]*backend.BigQuerySchema{},
+	}
+}
+
+// History keeps recent samples in memory, samples older than retention are trimmed as new
+// samples are added, so the number of samples kept does not depend on the sampling interval.
+type History struct {
+	sync.RWMutex
+
+	retention time.Duration
+	units     map[string][]*backend.BigQuerySchema
+}
+
+// Add stores a sample.
+func (h *History) Add(s *backend.BigQuerySchema) {
+	h.Lock()
+	defer h.Unlock()
+
+	samples := append(h.units[s.Name], s)
+	since := time.Now().Add(-h.retention)
+	expired := 0
+	for expired < len(samples) && samples[expired].Timestamp.Before(since) {
+		expired++
+	}
+	h.units[s.Name] = samples[expired:]
+}
+
+// OnSample stores a sample.
+func (h *History) OnSample(s *backend.BigQuerySchema) {
+	h.Add(s)
+}
+
+// OnEvent is a no-op, events are not kept in history.
+func (h *History) OnEvent(e *backend.EventSchema) {}
+
+// Query returns points matching a query ordered by unit, metric and time.
+func (h *History) Query(q Query) ([]Point, error) {
+	if q.Metric != "" {
+		if _, ok := metrics[q.Metric]; !ok {
+			return nil, fmt.Errorf("Unknown metric %s. Available metrics: %s", q.Metric, strings.Join(Metrics(), ", "))
+		}
+	}
+
+	since := time.Now().Add(-h.retention)
+	if q.Since.After(since) {
+		since = q.Since
+	}
+
+	points := []Point{}
+
+	h.RLock()
+	for unit, samples := range h.units {
+		if q.Unit != "" && q.Unit != unit {
+			continue
+		}
+
+		for _, s := range samples {
+			if s.Timestamp.Before(since) {
+				continue
+			}
+			for name, value := range metrics {
+				if q.Metric != "" && q.Metric != name {
+					continue
+				}
+				points = append(points, Point{
+					Unit:      unit,
+					Timestamp: s.Timestamp,
+					Metric:    name,
+					Value:     value(s),
+				})
+			}
+		}
+	}
+	h.RUnlock()
+
+	if q.Step > 0 {
+		points = downsample(points, q.Step)
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		if points[i].Unit != points[j].Unit {
+			return points[i].Unit < points[j].Unit
+		}
+		if points[i].Metric != points[j].Metric {
+			return points[i].Metric < points[j].Metric
+		}
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	return points, nil
+}
+
+// downsample averages points of the same unit and metric into step wide buckets.
+func downsample(points []Point, step time.Duration) []Point {
+	type key struct {
+		unit   string
+		metric string
+		start  int64
+	}
+
+	type bucket struct {
+		point Point
+		count int
+	}
+
+	buckets := map[key]*bucket{}
+	result := []Point{}
+	for _, p := range points {
+		start := p.Timestamp.Truncate(step)
+		k := key{unit: p.Unit, metric: p.Metric, start: start.UnixNano()}
+		b, ok := buckets[k]
+		if !ok {
+			b = &bucket{point: Point{Unit: p.Unit, Metric: p.Metric, Timestamp: start}}
+			buckets[k] = b
+		}
+		b.point.Value += p.Value
+		b.count++
+	}
+
+	for _, b := range buckets {
+		b.point.Value /= float64(b.count)
+		result = append(result, b.point)
+	}
+	return result
+}
+
+// Metrics returns available metric names.
+func Metrics() []string {
+	names := []string{}
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
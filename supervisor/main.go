@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/mesosphere/performance/supervisor/api"
@@ -18,5 +21,42 @@ func main() {
 	logrus.Infof("Collecting metrics every %s", interval.String())
 	logrus.Infof("Uploading metrics every %s", cfg.UploadInterval.String())
 
-	logrus.Fatal(api.StartWebServer(cfg))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dyn := config.NewDynamic(cfg)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				reload(dyn)
+				continue
+			}
+
+			logrus.Infof("Received %s, shutting down", sig)
+			cancel()
+			return
+		}
+	}()
+
+	if err := api.StartWebServer(ctx, dyn); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// reload applies reloadable settings from the config file, changes made through /config to
+// settings missing in the file are kept. Invalid settings are logged and the running config is kept.
+func reload(dyn *config.Dynamic) {
+	logrus.Info("Received SIGHUP, reloading config file")
+	r, err := dyn.Get().FileReloadable()
+	if err != nil {
+		logrus.Errorf("Error reloading config: %s", err)
+		return
+	}
+
+	if _, err := dyn.Reload(r); err != nil {
+		logrus.Errorf("Error reloading config: %s", err)
+	}
 }
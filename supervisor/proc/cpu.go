@@ -8,11 +8,13 @@ import (
 	"github.com/shirou/gopsutil/process"
 )
 
-// CPUPidUsage returns a cpu usage by pid.
+// CPUPidUsage returns a cpu usage by pid. Start and End are the measurement window.
 type CPUPidUsage struct {
 	User   float64
 	System float64
 	Total  float64
+	Start  time.Time
+	End    time.Time
 }
 
 // LoadByPID returns a CPU load (user, system) by a pid within the interval
@@ -30,6 +32,7 @@ func LoadByPID(pid int32, interval time.Duration) (*CPUPidUsage, error) {
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
 
 	time.Sleep(interval)
 
@@ -38,7 +41,14 @@ func LoadByPID(pid int32, interval time.Duration) (*CPUPidUsage, error) {
 		return nil, err
 	}
 
-	return calcCPULoad(before, after)
+	usage, err := calcCPULoad(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Start = start
+	usage.End = time.Now()
+	return usage, nil
 }
 
 type cpuTimes struct {
@@ -0,0 +1,46 @@
+package proc
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+)
+
+// LoadHost returns a CPU load of the host within the interval. Like LoadByPID, 100 is one
+// fully used CPU.
+func LoadHost(interval time.Duration) (*CPUPidUsage, error) {
+	if interval <= 0 {
+		panic("Interval cannot be negative or zero")
+	}
+
+	before, err := cpu.Times(false)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	time.Sleep(interval)
+
+	after, err := cpu.Times(false)
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+
+	total := after[0].Total() - before[0].Total()
+	if total <= 0 {
+		return &CPUPidUsage{Start: start, End: end}, nil
+	}
+
+	user := float64(runtime.NumCPU()) * (after[0].User - before[0].User) * 100 / total
+	system := float64(runtime.NumCPU()) * (after[0].System - before[0].System) * 100 / total
+
+	return &CPUPidUsage{
+		User:   user,
+		System: system,
+		Total:  user + system,
+		Start:  start,
+		End:    end,
+	}, nil
+}
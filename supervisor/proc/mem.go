@@ -0,0 +1,29 @@
+package proc
+
+import (
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/process"
+)
+
+// RSSByPID returns a resident set size of a pid in bytes.
+func RSSByPID(pid int32) (uint64, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := p.MemoryInfo()
+	if err != nil {
+		return 0, err
+	}
+	return info.RSS, nil
+}
+
+// HostMemoryUsed returns memory used on the host in bytes, excluding buffers and caches.
+func HostMemoryUsed() (uint64, error) {
+	v, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, err
+	}
+	return v.Total - v.Available, nil
+}
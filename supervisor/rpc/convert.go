@@ -0,0 +1,108 @@
+package rpc
+
+//go:generate protoc --go_out=plugins=grpc:. supervisor.proto
+
+import (
+	"time"
+
+	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/supervisor/run"
+)
+
+// ServiceName is a full name of the Supervisor gRPC service.
+const ServiceName = "performance.supervisor.Supervisor"
+
+// unixNano returns t in unix nanoseconds, 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano returns a time from unix nanoseconds, the zero time for 0.
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// NewEvent returns a message for an event.
+func NewEvent(e *backend.EventSchema) *Event {
+	labels := []*Label{}
+	for _, label := range e.Labels {
+		labels = append(labels, &Label{Key: label.Key, Value: label.Value})
+	}
+
+	return &Event{
+		Name:              e.Name,
+		TimestampUnixNano: unixNano(e.Timestamp),
+		Suite:             e.Suite,
+		Test:              e.Test,
+		Action:            e.Action,
+		Value:             e.Value,
+		Unit:              e.Unit,
+		DurationSec:       e.DurationSec,
+		Labels:            labels,
+		RunId:             e.RunID,
+		Hostname:          e.Hostname,
+		Instance:          e.Instance,
+	}
+}
+
+// Schema returns an event stored by the supervisor.
+func (m *Event) Schema() *backend.EventSchema {
+	e := &backend.EventSchema{
+		Name:        m.Name,
+		Timestamp:   fromUnixNano(m.TimestampUnixNano),
+		Suite:       m.Suite,
+		Test:        m.Test,
+		Action:      m.Action,
+		Value:       m.Value,
+		Unit:        m.Unit,
+		DurationSec: m.DurationSec,
+		RunID:       m.RunId,
+		Hostname:    m.Hostname,
+		Instance:    m.Instance,
+	}
+
+	for _, label := range m.Labels {
+		e.Labels = append(e.Labels, backend.EventLabel{Key: label.Key, Value: label.Value})
+	}
+	return e
+}
+
+// NewSample returns a message for a sample.
+func NewSample(s *backend.BigQuerySchema) *Sample {
+	return &Sample{
+		Name:                s.Name,
+		TimestampUnixNano:   unixNano(s.Timestamp),
+		UserCpuUsage:        s.UserCPU_Usage,
+		SystemCpuUsage:      s.SystemCPU_Usage,
+		TotalCpuUsage:       s.TotalCPU_Usage,
+		MemoryRss:           s.MemoryRSS,
+		Hostname:            s.Hostname,
+		Instance:            s.Instance,
+		RunId:               s.RunID,
+		TickUnixNano:        unixNano(s.Tick),
+		WindowStartUnixNano: unixNano(s.WindowStart),
+		WindowEndUnixNano:   unixNano(s.WindowEnd),
+	}
+}
+
+// NewRun returns a message for a run.
+func NewRun(r run.Run) *Run {
+	m := &Run{
+		Id:              r.ID,
+		Description:     r.Description,
+		Tags:            r.Tags,
+		Hostname:        r.Hostname,
+		StartedUnixNano: unixNano(r.Started),
+	}
+
+	if r.Stopped != nil {
+		m.StoppedUnixNano = unixNano(*r.Stopped)
+	}
+	return m
+}
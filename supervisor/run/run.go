@@ -0,0 +1,201 @@
+package run
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/backend"
+)
+
+// run lifecycle actions.
+const (
+	ActionStart = "start"
+	ActionStop  = "stop"
+)
+
+var (
+	// ErrNotFound is returned for an unknown run ID.
+	ErrNotFound = errors.New("Run not found")
+
+	// ErrActive is returned when a run is started while another run is active.
+	ErrActive = errors.New("Another run is active")
+
+	// ErrStopped is returned when a stopped run is stopped again.
+	ErrStopped = errors.New("Run is already stopped")
+)
+
+// Run is a scale test run. All samples collected while a run is active are stamped with its ID.
+type Run struct {
+	ID          string            `json:"id"`
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags"`
+	Hostname    string            `json:"hostname"`
+	Started     time.Time         `json:"started"`
+	Stopped     *time.Time        `json:"stopped,omitempty"`
+}
+
+// Active returns true if the run is not stopped.
+func (r Run) Active() bool {
+	return r.Stopped == nil
+}
+
+func (r Run) toRunSchema(action string, timestamp time.Time) *backend.RunSchema {
+	keys := []string{}
+	for key := range r.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := []backend.EventLabel{}
+	for _, key := range keys {
+		tags = append(tags, backend.EventLabel{Key: key, Value: r.Tags[key]})
+	}
+
+	return &backend.RunSchema{
+		RunID:       r.ID,
+		Action:      action,
+		Timestamp:   timestamp,
+		Description: r.Description,
+		Tags:        tags,
+		Hostname:    r.Hostname,
+	}
+}
+
+// recordsQueue is a number of run records waiting to be uploaded, a run produces two records.
+const recordsQueue = 100
+
+// NewRegistry returns a new instance of Registry. Run lifecycle records are queued on Records
+// and uploaded with other rows.
+func NewRegistry() *Registry {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logrus.Errorf("Unable to determine hostname: %s", err)
+		hostname = "<undefined>"
+	}
+
+	return &Registry{
+		hostname: hostname,
+		runs:     map[string]*Run{},
+		records:  make(chan *backend.BigQueryRow, recordsQueue),
+	}
+}
+
+// Registry keeps track of runs. At most one run is active at a time.
+type Registry struct {
+	sync.Mutex
+
+	hostname string
+	runs     map[string]*Run
+	active   string
+	records  chan *backend.BigQueryRow
+}
+
+// Start starts a new run.
+func (r *Registry) Start(description string, tags map[string]string) (Run, error) {
+	r.Lock()
+	if r.active != "" {
+		r.Unlock()
+		return Run{}, ErrActive
+	}
+
+	id, err := newID()
+	if err != nil {
+		r.Unlock()
+		return Run{}, err
+	}
+
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	run := &Run{
+		ID:          id,
+		Description: description,
+		Tags:        tags,
+		Hostname:    r.hostname,
+		Started:     time.Now(),
+	}
+	r.runs[id] = run
+	r.active = id
+	result := *run
+	r.Unlock()
+
+	logrus.Infof("Started run %s", id)
+	r.persist(result.toRunSchema(ActionStart, result.Started))
+	return result, nil
+}
+
+// Stop stops an active run.
+func (r *Registry) Stop(id string) (Run, error) {
+	r.Lock()
+	run, ok := r.runs[id]
+	if !ok {
+		r.Unlock()
+		return Run{}, ErrNotFound
+	}
+
+	if !run.Active() {
+		r.Unlock()
+		return *run, ErrStopped
+	}
+
+	stopped := time.Now()
+	run.Stopped = &stopped
+	if r.active == id {
+		r.active = ""
+	}
+	result := *run
+	r.Unlock()
+
+	logrus.Infof("Stopped run %s", id)
+	r.persist(result.toRunSchema(ActionStop, stopped))
+	return result, nil
+}
+
+// Get returns a run by ID.
+func (r *Registry) Get(id string) (Run, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	run, ok := r.runs[id]
+	if !ok {
+		return Run{}, ErrNotFound
+	}
+	return *run, nil
+}
+
+// ActiveID returns an ID of the active run or an empty string.
+func (r *Registry) ActiveID() string {
+	r.Lock()
+	defer r.Unlock()
+	return r.active
+}
+
+// Records returns a channel of run lifecycle rows to upload.
+func (r *Registry) Records() <-chan *backend.BigQueryRow {
+	return r.records
+}
+
+// persist queues a run record for upload without blocking, a run is tracked in memory regardless.
+func (r *Registry) persist(record *backend.RunSchema) {
+	select {
+	case r.records <- record.ToBigQueryRow():
+	default:
+		logrus.Errorf("Run records queue is full, dropping %s record of run %s", record.Action, record.RunID)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Unable to generate run ID: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/mesosphere/performance/supervisor/backend"
+)
+
+// message types.
+const (
+	TypeSample = "sample"
+	TypeEvent  = "event"
+)
+
+// Message is a single record pushed to subscribers.
+type Message struct {
+	Type string
+	Unit string
+	Data interface{}
+}
+
+// Filter selects messages for a subscriber. Empty fields match everything. Events have no unit
+// and are not filtered by Unit.
+type Filter struct {
+	Unit string
+	Type string
+}
+
+func (f Filter) match(m *Message) bool {
+	if f.Type != "" && f.Type != m.Type {
+		return false
+	}
+	if f.Unit != "" && m.Type != TypeEvent && f.Unit != m.Unit {
+		return false
+	}
+	return true
+}
+
+// Subscriber receives messages matching its filter on C.
+type Subscriber struct {
+	C <-chan *Message
+
+	c       chan *Message
+	filter  Filter
+	mu      sync.Mutex
+	dropped uint64
+}
+
+// Dropped returns a number of messages dropped because the subscriber was too slow.
+func (s *Subscriber) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// NewBroker returns a new instance of Broker. Every subscriber buffers up to size messages.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:        size,
+		subscribers: map[*Subscriber]struct{}{},
+	}
+}
+
+// Broker fans out samples and events to subscribers. Publishing never blocks, messages
+// are dropped for subscribers whose buffer is full.
+type Broker struct {
+	sync.RWMutex
+
+	size        int
+	subscribers map[*Subscriber]struct{}
+}
+
+// Subscribe adds a new subscriber.
+func (b *Broker) Subscribe(filter Filter) *Subscriber {
+	c := make(chan *Message, b.size)
+	s := &Subscriber{C: c, c: c, filter: filter}
+
+	b.Lock()
+	b.subscribers[s] = struct{}{}
+	b.Unlock()
+	return s
+}
+
+// Unsubscribe removes a subscriber and closes its channel.
+func (b *Broker) Unsubscribe(s *Subscriber) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.c)
+	}
+}
+
+// Publish sends a message to all matching subscribers.
+func (b *Broker) Publish(m *Message) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for s := range b.subscribers {
+		if !s.filter.match(m) {
+			continue
+		}
+
+		select {
+		case s.c <- m:
+		default:
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+		}
+	}
+}
+
+// OnSample publishes a sample.
+func (b *Broker) OnSample(s *backend.BigQuerySchema) {
+	b.Publish(&Message{Type: TypeSample, Unit: s.Name, Data: s})
+}
+
+// OnEvent publishes an event.
+func (b *Broker) OnEvent(e *backend.EventSchema) {
+	b.Publish(&Message{Type: TypeEvent, Data: e})
+}
@@ -13,19 +13,35 @@ type SystemdUnitProps struct {
 	Name string
 }
 
-// GetSystemdUnitsProps returns a list of systemd units available on a system.
-func GetSystemdUnitsProps() ([]*SystemdUnitProps, error) {
+// reasons a unit is skipped.
+const (
+	SkipNotService = "not-service"
+	SkipInvalidPID = "invalid-main-pid"
+	SkipNoPID      = "no-main-pid"
+	SkipSSHSession = "ssh-session"
+)
+
+// SkippedUnit describes a systemd unit which cannot be watched.
+type SkippedUnit struct {
+	Name   string
+	Reason string
+}
+
+// GetSystemdUnitsProps returns a list of systemd units available on a system and a list of
+// units skipped with a reason.
+func GetSystemdUnitsProps() ([]*SystemdUnitProps, []*SkippedUnit, error) {
 	conn, err := dbus.New()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer conn.Close()
 
 	uProps := []*SystemdUnitProps{}
+	skipped := []*SkippedUnit{}
 
 	unitStatuses, err := conn.ListUnits()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, unitStatus := range unitStatuses {
@@ -33,6 +49,7 @@ func GetSystemdUnitsProps() ([]*SystemdUnitProps, error) {
 		if err != nil {
 			// skip non service units or the ones lacking MainPID.
 			logrus.Debugf("Skipped %s: %s", unitStatus.Name, err)
+			skipped = append(skipped, &SkippedUnit{Name: unitStatus.Name, Reason: SkipNotService})
 			continue
 		}
 
@@ -40,16 +57,19 @@ func GetSystemdUnitsProps() ([]*SystemdUnitProps, error) {
 		if !ok {
 			// expecting uint32 value for mainPID
 			logrus.Debugf("Skipped unit %s value %v cannot be type asserted to uint32", unitStatus.Name, prop.Value.Value())
+			skipped = append(skipped, &SkippedUnit{Name: unitStatus.Name, Reason: SkipInvalidPID})
 			continue
 		}
 
 		if mainPID == 0 {
-			logrus.Debugf("Skipped unit %s has MainPID value = 0.")
+			logrus.Debugf("Skipped unit %s has MainPID value = 0.", unitStatus.Name)
+			skipped = append(skipped, &SkippedUnit{Name: unitStatus.Name, Reason: SkipNoPID})
 			continue
 		}
 
-		if strings.HasPrefix(prop.Name, "ssh@") {
+		if strings.HasPrefix(unitStatus.Name, "ssh@") {
 			logrus.Debugf("Skipped ssh session")
+			skipped = append(skipped, &SkippedUnit{Name: unitStatus.Name, Reason: SkipSSHSession})
 			continue
 		}
 
@@ -58,5 +78,5 @@ func GetSystemdUnitsProps() ([]*SystemdUnitProps, error) {
 			Name: unitStatus.Name,
 		})
 	}
-	return uProps, nil
+	return uProps, skipped, nil
 }
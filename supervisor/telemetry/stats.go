@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewStats returns a new instance of Stats.
+func NewStats() *Stats {
+	return &Stats{
+		started:      time.Now(),
+		unitsSkipped: map[string]uint64{},
+		backends:     map[string]*BackendStats{},
+	}
+}
+
+// Stats holds supervisor pipeline counters. It is safe for concurrent use.
+type Stats struct {
+	sync.Mutex
+
+	started          time.Time
+	samplesCollected uint64
+	collectRounds    uint64
+	unitsSkipped     map[string]uint64
+	resultsDropped   uint64
+	memoryErrors     uint64
+	rowsBuffered     int
+	eventsIngested   uint64
+	eventsRejected   uint64
+	backends         map[string]*BackendStats
+}
+
+// BackendStats holds upload counters for a single backend.
+type BackendStats struct {
+	UploadsSucceeded  uint64    `json:"uploads_succeeded"`
+	UploadsFailed     uint64    `json:"uploads_failed"`
+	LastSuccess       time.Time `json:"last_success"`
+	LastError         string    `json:"last_error,omitempty"`
+	FailingSince      time.Time `json:"failing_since"`
+	ConsecutiveErrors uint64    `json:"consecutive_errors"`
+	RowsDropped       uint64    `json:"rows_dropped"`
+}
+
+// Status is a point in time snapshot of Stats.
+type Status struct {
+	Uptime           string                  `json:"uptime"`
+	SamplesCollected uint64                  `json:"samples_collected"`
+	CollectRounds    uint64                  `json:"collect_rounds"`
+	UnitsSkipped     map[string]uint64       `json:"units_skipped"`
+	ResultsDropped   uint64                  `json:"results_dropped"`
+	MemoryErrors     uint64                  `json:"memory_errors"`
+	RowsBuffered     int                     `json:"rows_buffered"`
+	EventsIngested   uint64                  `json:"events_ingested"`
+	EventsRejected   uint64                  `json:"events_rejected"`
+	LastUpload       time.Time               `json:"last_upload"`
+	Backends         map[string]BackendStats `json:"backends"`
+}
+
+// AddSamples increments a number of collected samples.
+func (s *Stats) AddSamples(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.samplesCollected += uint64(n)
+}
+
+// CollectRound marks a finished collection round.
+func (s *Stats) CollectRound() {
+	s.Lock()
+	defer s.Unlock()
+	s.collectRounds++
+}
+
+// SkipUnit increments a number of units skipped for a given reason.
+func (s *Stats) SkipUnit(reason string) {
+	s.Lock()
+	defer s.Unlock()
+	s.unitsSkipped[reason]++
+}
+
+// DropResults increments a number of measurements dropped because the results queue was full.
+func (s *Stats) DropResults(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.resultsDropped += uint64(n)
+}
+
+// MemoryError increments a number of samples reported without memory because it could not be measured.
+func (s *Stats) MemoryError() {
+	s.Lock()
+	defer s.Unlock()
+	s.memoryErrors++
+}
+
+// SetRowsBuffered sets a number of rows waiting for upload.
+func (s *Stats) SetRowsBuffered(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.rowsBuffered = n
+}
+
+// AddEvents increments a number of events ingested.
+func (s *Stats) AddEvents(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.eventsIngested += uint64(n)
+}
+
+// RejectEvents increments a number of events rejected by the API.
+func (s *Stats) RejectEvents(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.eventsRejected += uint64(n)
+}
+
+// Upload records a result of upload to a backend. err is nil on success.
+func (s *Stats) Upload(backendID string, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	b := s.backend(backendID)
+	if err == nil {
+		b.UploadsSucceeded++
+		b.LastSuccess = time.Now()
+		b.FailingSince = time.Time{}
+		b.ConsecutiveErrors = 0
+		return
+	}
+
+	b.UploadsFailed++
+	b.LastError = err.Error()
+	if b.ConsecutiveErrors == 0 {
+		b.FailingSince = time.Now()
+	}
+	b.ConsecutiveErrors++
+}
+
+// DropRows increments a number of rows dropped because too many were pending for a failing backend.
+func (s *Stats) DropRows(backendID string, n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.backend(backendID).RowsDropped += uint64(n)
+}
+
+// backend returns counters of a backend, creating them on first use.
+func (s *Stats) backend(backendID string) *BackendStats {
+	b, ok := s.backends[backendID]
+	if !ok {
+		b = &BackendStats{}
+		s.backends[backendID] = b
+	}
+	return b
+}
+
+// Status returns a snapshot of all counters.
+func (s *Stats) Status() Status {
+	s.Lock()
+	defer s.Unlock()
+
+	status := Status{
+		Uptime:           time.Since(s.started).String(),
+		SamplesCollected: s.samplesCollected,
+		CollectRounds:    s.collectRounds,
+		UnitsSkipped:     map[string]uint64{},
+		ResultsDropped:   s.resultsDropped,
+		MemoryErrors:     s.memoryErrors,
+		RowsBuffered:     s.rowsBuffered,
+		EventsIngested:   s.eventsIngested,
+		EventsRejected:   s.eventsRejected,
+		Backends:         map[string]BackendStats{},
+	}
+
+	for reason, n := range s.unitsSkipped {
+		status.UnitsSkipped[reason] = n
+	}
+
+	for id, b := range s.backends {
+		status.Backends[id] = *b
+		if b.LastSuccess.After(status.LastUpload) {
+			status.LastUpload = b.LastSuccess
+		}
+	}
+	return status
+}
+
+// Healthy returns an error if uploads to any backend have been failing for longer than threshold.
+func (s *Stats) Healthy(threshold time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, b := range s.backends {
+		if b.ConsecutiveErrors > 0 && time.Since(b.FailingSince) > threshold {
+			return fmt.Errorf("Uploads to %s failing for %s: %s", id, time.Since(b.FailingSince), b.LastError)
+		}
+	}
+	return nil
+}
+
+// Ready returns an error if the supervisor has not finished a collection round yet or
+// is not healthy.
+func (s *Stats) Ready(threshold time.Duration) error {
+	s.Lock()
+	rounds := s.collectRounds
+	s.Unlock()
+
+	if rounds == 0 {
+		return fmt.Errorf("No metrics collected yet")
+	}
+	return s.Healthy(threshold)
+}
@@ -0,0 +1,111 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/supervisor/telemetry"
+)
+
+// maxRetainedBatches is how many batches of rows are kept for a failing backend, older rows are dropped.
+const maxRetainedBatches = 10
+
+// batch buffers rows of any type until they are uploaded together. Rows are pending per backend,
+// so a failing backend does not make other backends receive the same rows twice.
+type batch struct {
+	size     int
+	backends []backend.Backend
+	stats    *telemetry.Stats
+
+	// rows waiting for upload by backend ID.
+	pending map[string][]*backend.BigQueryRow
+	// backends which failed the last upload, they are retried on the upload interval only.
+	failing map[string]bool
+	// rows added since the last flush.
+	fresh int
+}
+
+func newBatch(size int, backends []backend.Backend, stats *telemetry.Stats) *batch {
+	return &batch{
+		size:     size,
+		backends: backends,
+		stats:    stats,
+		pending:  map[string][]*backend.BigQueryRow{},
+		failing:  map[string]bool{},
+	}
+}
+
+// add appends a row for every backend and reports whether size rows have been added since the
+// last flush. Rows over maxRetainedBatches batches are dropped, oldest first.
+func (b *batch) add(row *backend.BigQueryRow) bool {
+	limit := b.size * maxRetainedBatches
+	for _, be := range b.backends {
+		id := be.ID()
+		rows := append(b.pending[id], row)
+		if len(rows) > limit {
+			dropped := len(rows) - limit
+			logrus.Debugf("Dropping %d rows pending for backend %s", dropped, id)
+			b.stats.DropRows(id, dropped)
+			rows = rows[dropped:]
+		}
+		b.pending[id] = rows
+	}
+
+	b.fresh++
+	b.stats.SetRowsBuffered(b.buffered())
+	return b.fresh >= b.size
+}
+
+// full reports whether size rows have been added since the last flush.
+func (b *batch) full() bool {
+	return b.fresh >= b.size
+}
+
+// buffered returns the largest number of rows pending for a backend.
+func (b *batch) buffered() int {
+	n := 0
+	for _, rows := range b.pending {
+		if len(rows) > n {
+			n = len(rows)
+		}
+	}
+	return n
+}
+
+// flush uploads pending rows to every backend. Rows are kept for backends which fail and retried
+// on the next flush with retry set, backends which failed the last upload are skipped otherwise.
+func (b *batch) flush(ctx context.Context, retry bool) error {
+	b.fresh = 0
+
+	errs := []string{}
+	for _, be := range b.backends {
+		id := be.ID()
+		rows := b.pending[id]
+		if len(rows) == 0 || (b.failing[id] && !retry) {
+			continue
+		}
+
+		logrus.Debugf("Uploading %d rows to %s", len(rows), id)
+		err := be.Put(ctx, rows)
+		b.stats.Upload(id, err)
+		if err != nil {
+			b.failing[id] = true
+			errs = append(errs, fmt.Sprintf("Error uploading to backend %s: %s", id, err))
+			continue
+		}
+
+		logrus.Infof("Uploaded to storage %s", id)
+		delete(b.failing, id)
+		delete(b.pending, id)
+	}
+
+	b.stats.SetRowsBuffered(b.buffered())
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
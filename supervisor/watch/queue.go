@@ -0,0 +1,58 @@
+package watch
+
+import (
+	"sync/atomic"
+
+	"github.com/mesosphere/performance/supervisor/config"
+	"github.com/mesosphere/performance/supervisor/telemetry"
+)
+
+// resultQueue is a bounded queue of measurements between unit workers and the result processor.
+// Pushing never blocks, when the queue is full a result is dropped according to the policy.
+type resultQueue struct {
+	c       chan *SystemdUnitStatus
+	policy  string
+	stats   *telemetry.Stats
+	dropped uint64
+}
+
+func newResultQueue(capacity int, policy string, stats *telemetry.Stats) *resultQueue {
+	return &resultQueue{
+		c:      make(chan *SystemdUnitStatus, capacity),
+		policy: policy,
+		stats:  stats,
+	}
+}
+
+// push queues a result. With config.DropNewest the result itself is dropped if the queue is full,
+// with config.DropOldest the oldest queued result is dropped to make room.
+func (q *resultQueue) push(r *SystemdUnitStatus) {
+	for {
+		select {
+		case q.c <- r:
+			return
+		default:
+		}
+
+		if q.policy == config.DropNewest {
+			q.drop()
+			return
+		}
+
+		select {
+		case <-q.c:
+			q.drop()
+		default:
+		}
+	}
+}
+
+func (q *resultQueue) drop() {
+	atomic.AddUint64(&q.dropped, 1)
+	q.stats.DropResults(1)
+}
+
+// Dropped returns a total number of dropped results.
+func (q *resultQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
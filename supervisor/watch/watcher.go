@@ -12,58 +12,149 @@ import (
 	"github.com/mesosphere/performance/supervisor/backend"
 	"github.com/mesosphere/performance/supervisor/config"
 	"github.com/mesosphere/performance/supervisor/proc"
+	"github.com/mesosphere/performance/supervisor/run"
 	"github.com/mesosphere/performance/supervisor/systemd"
+	"github.com/mesosphere/performance/supervisor/telemetry"
 )
 
+// reasons a unit is skipped by the watcher.
+const (
+	// skipLoadError is a reason a unit is skipped when its cpu usage cannot be measured.
+	skipLoadError = "load-error"
+
+	// skipFiltered is a reason a unit is skipped when it does not match unit filters.
+	skipFiltered = "filtered"
+)
+
+// HostUnit is a name of host wide samples, collected along with systemd units.
+const HostUnit = "host"
+
 type Event map[string]interface{}
 
-// StartWatcher starts watching host systemd units
-func StartWatcher(ctx context.Context, cfg *config.Config, backends []backend.Backend,
-	eventChan <-chan *backend.BigQuerySchema) {
+// Observer is notified about every sample and event processed by the watcher. Observers
+// are called from the result processor and must not block.
+type Observer interface {
+	OnSample(*backend.BigQuerySchema)
+	OnEvent(*backend.EventSchema)
+}
+
+// StartWatcher starts watching host systemd units. It returns when ctx is canceled and
+// buffered rows have been flushed to backends. Config changes apply from the next collection round.
+func StartWatcher(ctx context.Context, dyn *config.Dynamic, backends []backend.Backend,
+	eventChan <-chan *backend.EventSchema, stats *telemetry.Stats, runs *run.Registry, observers ...Observer) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	resultChan := make(chan *SystemdUnitStatus)
+	// queue capacity and drop policy are fixed for the watcher lifetime.
+	cfg := dyn.Get()
+	results := newResultQueue(cfg.FlagResultsQueue, cfg.FlagDropPolicy, stats)
+	done := make(chan struct{})
 
-	go processResult(ctx, cfg, resultChan, backends, eventChan)
+	go func() {
+		processResult(ctx, dyn, results.c, backends, eventChan, stats, runs, observers)
+		close(done)
+	}()
 
+	next := time.Now()
 	for {
-		if err := processUnits(ctx, cfg, resultChan); err != nil {
-			logrus.Error(err)
+		cfg, changed := dyn.Watch()
+		if cfg.FlagAlignTicks {
+			next = nextTick(time.Now(), cfg.Wait)
 		}
 
 		select {
 		case <-ctx.Done():
 			logrus.Info("Shutting down watcher")
-			close(resultChan)
+			<-done
+			return
 
-		case <-time.After(cfg.Wait):
+		case <-changed:
+			logrus.Info("Watcher config changed")
+			next = time.Now()
+			continue
+
+		case <-time.After(next.Sub(time.Now())):
 		}
+
+		if err := processUnits(ctx, cfg, next, results, stats); err != nil {
+			logrus.Error(err)
+		} else {
+			stats.CollectRound()
+		}
+
+		if cfg.FlagAlignTicks && time.Since(next) > cfg.Wait {
+			logrus.Warningf("Collection round took %s, longer than interval %s, skipping ticks", time.Since(next), cfg.Wait)
+		}
+		next = time.Now().Add(cfg.Wait)
 	}
 }
 
-func processUnits(ctx context.Context, cfg *config.Config, resultChan chan<- *SystemdUnitStatus) error {
-	units, err := systemd.GetSystemdUnitsProps()
+// nextTick returns the first wall-clock multiple of interval after now.
+func nextTick(now time.Time, interval time.Duration) time.Time {
+	return now.Truncate(interval).Add(interval)
+}
+
+// processUnits measures all units with at most cfg.FlagWorkers units at a time. tick is a scheduled
+// time of the collection round.
+func processUnits(ctx context.Context, cfg *config.Config, tick time.Time, results *resultQueue,
+	stats *telemetry.Stats) error {
+	units, skipped, err := systemd.GetSystemdUnitsProps()
 	if err != nil {
 		return fmt.Errorf("Unable to get a list of systemd units: %s", err)
 	}
 
+	for _, unit := range skipped {
+		stats.SkipUnit(unit.Reason)
+	}
+
+	dropped := results.Dropped()
 	wg := &sync.WaitGroup{}
-	for _, unit := range units {
+	wg.Add(1)
+	go handleHost(cfg, tick, wg, results, stats)
+
+	jobs := make(chan *systemd.SystemdUnitProps)
+	for i := 0; i < cfg.FlagWorkers; i++ {
 		wg.Add(1)
-		go handleUnit(ctx, unit, cfg, wg, resultChan)
+		go func() {
+			defer wg.Done()
+			for unit := range jobs {
+				handleUnit(unit, cfg, tick, results, stats)
+			}
+		}()
+	}
+
+loop:
+	for _, unit := range units {
+		if !cfg.MatchUnit(unit.Name) {
+			stats.SkipUnit(skipFiltered)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case jobs <- unit:
+		}
 	}
+	close(jobs)
 
 	wg.Wait()
+
+	if n := results.Dropped() - dropped; n > 0 {
+		logrus.Warningf("Results queue is full, dropped %d results (%s)", n, cfg.FlagDropPolicy)
+	}
 	return nil
 }
 
-// SystemdUnitStatus a structure that holds systemd unit name, pid and cpu utilization by it.
+// SystemdUnitStatus a structure that holds systemd unit name, pid and cpu and memory utilization by it.
+// Host samples are named HostUnit and have zero pid.
 type SystemdUnitStatus struct {
 	Name     string
 	Pid      uint32
+	Tick     time.Time
 	CPUUsage *proc.CPUPidUsage
+	RSS      uint64
 }
 
 func (s *SystemdUnitStatus) ToBigQuerySchema() *backend.BigQuerySchema {
@@ -71,80 +162,180 @@ func (s *SystemdUnitStatus) ToBigQuerySchema() *backend.BigQuerySchema {
 		Name:            s.Name,
 		Timestamp:       time.Now(),
 		UserCPU_Usage:   s.CPUUsage.User,
-		SystemCPU_Usage: s.CPUUsage.User,
+		SystemCPU_Usage: s.CPUUsage.System,
 		TotalCPU_Usage:  s.CPUUsage.Total,
+		MemoryRSS:       int64(s.RSS),
 		Instance:        strconv.Itoa(int(s.Pid)),
+		Tick:            s.Tick,
+		WindowStart:     s.CPUUsage.Start,
+		WindowEnd:       s.CPUUsage.End,
 	}
 }
 
-func handleUnit(ctx context.Context, unit *systemd.SystemdUnitProps, cfg *config.Config, wg *sync.WaitGroup, resultChan chan<- *SystemdUnitStatus) {
-	defer wg.Done()
-
+func handleUnit(unit *systemd.SystemdUnitProps, cfg *config.Config, tick time.Time, results *resultQueue,
+	stats *telemetry.Stats) {
 	usage, err := proc.LoadByPID(int32(unit.Pid), cfg.CPUUsageInterval)
 	if err != nil {
 		logrus.Errorf("Unit %s. Error %s", unit.Name, err)
+		stats.SkipUnit(skipLoadError)
 		return
 	}
 
-	select {
-	case <-ctx.Done():
+	// the cpu sample is still reported if memory cannot be measured, with RSS 0.
+	rss, err := proc.RSSByPID(int32(unit.Pid))
+	if err != nil {
+		logrus.Errorf("Unit %s. Error measuring memory: %s", unit.Name, err)
+		stats.MemoryError()
+	}
+
+	results.push(&SystemdUnitStatus{
+		Name:     unit.Name,
+		Pid:      unit.Pid,
+		Tick:     tick,
+		CPUUsage: usage,
+		RSS:      rss,
+	})
+}
+
+// handleHost measures host cpu usage within the same interval as units and used memory.
+func handleHost(cfg *config.Config, tick time.Time, wg *sync.WaitGroup, results *resultQueue, stats *telemetry.Stats) {
+	defer wg.Done()
+
+	usage, err := proc.LoadHost(cfg.CPUUsageInterval)
+	if err != nil {
+		logrus.Errorf("Host. Error %s", err)
+		stats.SkipUnit(skipLoadError)
 		return
-	default:
-		resultChan <- &SystemdUnitStatus{
-			Name:     unit.Name,
-			Pid:      unit.Pid,
-			CPUUsage: usage,
-		}
 	}
+
+	used, err := proc.HostMemoryUsed()
+	if err != nil {
+		logrus.Errorf("Host. Error measuring memory: %s", err)
+		stats.MemoryError()
+	}
+
+	results.push(&SystemdUnitStatus{
+		Name:     HostUnit,
+		Tick:     tick,
+		CPUUsage: usage,
+		RSS:      used,
+	})
 }
 
-func processResult(ctx context.Context, cfg *config.Config, results <-chan *SystemdUnitStatus,
-	backends []backend.Backend, eventChan <-chan *backend.BigQuerySchema) {
-	rows := []*backend.BigQueryRow{}
-	updateTime := time.Now()
+// processResult buffers samples, events and run records and uploads them in batches, every
+// cfg.UploadInterval or as soon as cfg.FlagBufferSize rows are buffered. Rows are stamped with the
+// active run ID.
+func processResult(ctx context.Context, dyn *config.Dynamic, results <-chan *SystemdUnitStatus,
+	backends []backend.Backend, eventChan <-chan *backend.EventSchema, stats *telemetry.Stats, runs *run.Registry,
+	observers []Observer) {
+	cfg, changed := dyn.Watch()
+	rows := newBatch(cfg.FlagBufferSize, backends, stats)
 	hostname, err := os.Hostname()
 	if err != nil {
 		logrus.Errorf("Unable to determine hostname: %s", err)
 		hostname = "<undefined>"
 	}
 
+	addEvent := func(event *backend.EventSchema) bool {
+		stats.AddEvents(1)
+		if event.RunID == "" {
+			event.RunID = runs.ActiveID()
+		}
+		for _, o := range observers {
+			o.OnEvent(event)
+		}
+		return rows.add(event.ToBigQueryRow())
+	}
+
+	addResult := func(result *SystemdUnitStatus) bool {
+		logrus.Debugf("[%s]: User %f; System %f; Total %f", result.Name,
+			result.CPUUsage.User, result.CPUUsage.System, result.CPUUsage.Total)
+
+		row := result.ToBigQuerySchema()
+		row.Hostname = hostname
+		row.RunID = runs.ActiveID()
+		for _, o := range observers {
+			o.OnSample(row)
+		}
+
+		stats.AddSamples(1)
+		return rows.add(row.ToBigQueryRow())
+	}
+
+	ticker := time.NewTicker(cfg.UploadInterval)
+	defer func() {
+		ticker.Stop()
+	}()
+
 	for {
+		full, retry := false, false
+
 		select {
 		case <-ctx.Done():
 			logrus.Infof("Shutting down result processor")
+			// servers are stopped by now, so rows already queued are the last ones.
+			drain(eventChan, results, runs.Records(), addEvent, addResult, rows)
+			flush(dyn.Get(), rows)
 			return
 
+		case <-changed:
+			// buffered rows are kept, only the upload schedule and threshold change.
+			cfg, changed = dyn.Watch()
+			ticker.Stop()
+			ticker = time.NewTicker(cfg.UploadInterval)
+			rows.size = cfg.FlagBufferSize
+			full = rows.full()
+
 		case event := <-eventChan:
-			if err := upload(ctx, []*backend.BigQueryRow{event.ToBigQueryRow()}, backends); err != nil {
-				logrus.Errorf("Error saving a new event: %s", err)
-			}
+			full = addEvent(event)
+
+		case record := <-runs.Records():
+			full = rows.add(record)
 
 		case result := <-results:
-			logrus.Debugf("[%s]: User %f; System %f; Total %f", result.Name,
-				result.CPUUsage.User, result.CPUUsage.System, result.CPUUsage.Total)
-
-			row := result.ToBigQuerySchema()
-			row.Hostname = hostname
-
-			rows = append(rows, row.ToBigQueryRow())
-			if len(rows) >= cfg.FlagBufferSize || time.Since(updateTime) >= cfg.UploadInterval {
-				if err := upload(ctx, rows, backends); err != nil {
-					logrus.Error(err)
-					continue
-				}
-				rows = []*backend.BigQueryRow{}
-				updateTime = time.Now()
+			full = addResult(result)
+
+		case <-ticker.C:
+			// failing backends are retried on the upload interval only, not as rows come in.
+			full, retry = true, true
+		}
+
+		if full {
+			if err := rows.flush(ctx, retry); err != nil {
+				logrus.Error(err)
 			}
 		}
 	}
 }
 
-func upload(ctx context.Context, items interface{}, backends []backend.Backend) error {
-	for _, b := range backends {
-		if err := b.Put(ctx, items); err != nil {
-			return fmt.Errorf("Error uploading to backend %s: %s", b.ID(), err)
+// drain adds rows which are already queued to the batch without blocking.
+func drain(events <-chan *backend.EventSchema, results <-chan *SystemdUnitStatus, records <-chan *backend.BigQueryRow,
+	addEvent func(*backend.EventSchema) bool, addResult func(*SystemdUnitStatus) bool, rows *batch) {
+	for {
+		select {
+		case event := <-events:
+			addEvent(event)
+		case result := <-results:
+			addResult(result)
+		case record := <-records:
+			rows.add(record)
+		default:
+			return
 		}
-		logrus.Infof("Uploaded to storage %s", b.ID())
 	}
-	return nil
+}
+
+// flush uploads remaining rows on shutdown, bounded by cfg.ShutdownTimeout.
+func flush(cfg *config.Config, rows *batch) {
+	if rows.buffered() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	logrus.Infof("Flushing %d buffered rows", rows.buffered())
+	if err := rows.flush(ctx, true); err != nil {
+		logrus.Errorf("Error flushing buffered rows: %s", err)
+	}
 }
@@ -1,19 +1,45 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/mesosphere/performance/test"
+	"github.com/mesosphere/performance/test/http"
 	"github.com/mesosphere/performance/test/suite/journald"
 )
 
 var (
-	testType      = flag.String("test-type", "constant-rate", "The type of test to run")
-	supervisorURL = flag.String("supervisor-url", "http://localhost:9123/incoming", "host:port to POST events to")
+	testType      = flag.String("test-type", "constant-rate", "The type of test to run, constant-rate, ramp, burst or multi-writer")
+	supervisorURL = flag.String("supervisor-url", "http://localhost:9123/incoming", "URL to send events to, http(s)://, unix:///path/to/supervisor.sock or grpc(s)://host:port")
 	logRate       = flag.Int("log-rate", 1000, "Rate of logs sent to STDOUT in lines per second")
 	stdErr        = flag.Bool("stderr", false, "Sends log lines to STDERR in conjunction with STDOUT")
 	testDuration  = flag.Int("duration", 60, "Test duration in seconds")
+	lineSize      = flag.Int("line-size", journald.DefaultLineSize, "Size of log lines in bytes, including the newline")
+	rampStart     = flag.Int("ramp-start-rate", journald.DefaultRampStartRate, "Rate the ramp test starts at, it ends at -log-rate")
+	rampSteps     = flag.Int("ramp-steps", journald.DefaultRampSteps, "Number of ramp test steps")
+	rampCurve     = flag.String("ramp-curve", journald.RAMP_LINEAR, "Ramp test curve, linear or exponential")
+	rateLimit     = flag.Duration("rate-limit-interval", journald.DefaultRateLimitInterval, "Journald RateLimitIntervalSec, ramp test suppressions are attributed to the step active when their window began")
+	burstSize     = flag.Int("burst-size", journald.DefaultBurstSize, "Number of lines in each burst of the burst test")
+	burstGap      = flag.Duration("burst-gap", journald.DefaultBurstGap, "Idle time between bursts of the burst test")
+	verify        = flag.Bool("verify", false, "Read written lines back from the journal and report delivery and latency")
+	verifyGrace   = flag.Duration("verify-grace", journald.DefaultVerifyGrace, "Time to wait for written lines to reach the journal after the test")
+	writers       = flag.Int("writers", journald.DefaultWriters, "Number of concurrent writers of the multi-writer test")
+	writerRates   = flag.String("writer-rates", "", "Comma separated rates of multi-writer test writers, repeated if there are more writers, -log-rate if empty")
+	writerMode    = flag.String("writer-mode", journald.WRITER_GOROUTINE, "Multi-writer test writers, goroutine or unit to run each writer in its own transient systemd unit")
+	writerRunID   = flag.String(journald.WriterRunIDFlag, "", "Internal, runs a single multi-writer test writer")
+	writerResult  = flag.String(journald.WriterResultFlag, "", "Internal, file a multi-writer test writer stores its written lines to")
+	resultFile    = flag.String("result-file", "", "Write the suite result as JSON to a file")
+
+	supervisorToken = flag.String("supervisor-token", "", "Bearer token to authenticate to the supervisor")
+	supervisorCA    = flag.String("supervisor-ca", "", "CA file to verify the supervisor TLS certificate")
+	clientCert      = flag.String("client-cert", "", "Client certificate file presented to the supervisor")
+	clientKey       = flag.String("client-key", "", "Client certificate key file")
 
 	log = logrus.WithFields(logrus.Fields{
 		"suite": "jounrald-test-exec",
@@ -23,14 +49,58 @@ var (
 func main() {
 	flag.Parse()
 
+	// interrupting the suite stops running testers, teardown still runs.
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-signals
+		log.Infof("Received %s, stopping tests", s)
+		cancel()
+	}()
+
+	// started by the multi-writer test in a writer unit.
+	if *writerRunID != "" {
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(*testDuration)*time.Second)
+		defer cancel()
+
+		if err := journald.RunWriter(ctx, *writerRunID, *logRate, *lineSize, *writerResult); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	rates, err := journald.ParseRates(*writerRates)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	clientOptions := []http.ClientOption{http.ClientOptionToken(*supervisorToken)}
+	if *supervisorCA != "" {
+		clientOptions = append(clientOptions, http.ClientOptionCA(*supervisorCA))
+	}
+	if *clientCert != "" {
+		clientOptions = append(clientOptions, http.ClientOptionCertificate(*clientCert, *clientKey))
+	}
+
+	if err := http.SetDefaultClient(clientOptions...); err != nil {
+		log.Fatal(err)
+	}
+
 	journaldSuite, err := journald.NewTestSuite(
 		*logRate,
 		*testDuration,
 		*testType,
-		*stdErr)
+		*stdErr,
+		journald.OptionLineSize(*lineSize),
+		journald.OptionRamp(*rampStart, *rampSteps, *rampCurve),
+		journald.OptionRateLimitInterval(*rateLimit),
+		journald.OptionBurst(*burstSize, *burstGap),
+		journald.OptionVerify(*verify, *verifyGrace),
+		journald.OptionWriters(*writers, rates, *writerMode))
 
 	if err != nil {
-		log.Fatalf(err.Error())
+		log.Fatal(err)
 	}
 
 	testSuite, err := test.NewSuite(
@@ -38,8 +108,22 @@ func main() {
 		test.OptionTesters(journaldSuite))
 
 	if err != nil {
-		log.Fatalf(err.Error())
+		log.Fatal(err)
+	}
+
+	result := testSuite.Start(ctx)
+
+	if err := http.DefaultClient.Close(); err != nil {
+		log.Error(err)
+		result.Passed = false
+	}
+
+	result.Log()
+	if *resultFile != "" {
+		if err := result.WriteFile(*resultFile); err != nil {
+			log.Error(err)
+		}
 	}
 
-	testSuite.Start()
+	os.Exit(result.ExitCode())
 }
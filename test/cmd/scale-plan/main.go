@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/test"
+	"github.com/mesosphere/performance/test/http"
+	"github.com/mesosphere/performance/test/plan"
+	"github.com/mesosphere/performance/test/suite/journald"
+)
+
+var (
+	planFile      = flag.String("plan", "", "Test plan file in JSON format")
+	dryRun        = flag.Bool("dry-run", false, "Print the expanded plan points and exit")
+	supervisorURL = flag.String("supervisor-url", "http://localhost:9123/incoming", "URL to send events to, http(s)://, unix:///path/to/supervisor.sock or grpc(s)://host:port")
+	resultFile    = flag.String("result-file", "", "Write the suite result as JSON to a file")
+
+	supervisorToken = flag.String("supervisor-token", "", "Bearer token to authenticate to the supervisor")
+	supervisorCA    = flag.String("supervisor-ca", "", "CA file to verify the supervisor TLS certificate")
+	clientCert      = flag.String("client-cert", "", "Client certificate file presented to the supervisor")
+	clientKey       = flag.String("client-key", "", "Client certificate key file")
+
+	// multi-writer test writers in the unit mode run this executable with these flags.
+	writerRunID    = flag.String(journald.WriterRunIDFlag, "", "Internal, runs a single multi-writer test writer")
+	writerResult   = flag.String(journald.WriterResultFlag, "", "Internal, file a multi-writer test writer stores its written lines to")
+	writerRate     = flag.Int("log-rate", 1000, "Internal, lines per second of a multi-writer test writer")
+	writerLineSize = flag.Int("line-size", journald.DefaultLineSize, "Internal, line size of a multi-writer test writer")
+	writerDuration = flag.Int("duration", 60, "Internal, seconds a multi-writer test writer runs")
+
+	log = logrus.WithFields(logrus.Fields{
+		"suite": "scale-plan-exec",
+	})
+)
+
+// factories are suites which can be used in a test plan.
+var factories = map[string]plan.Factory{
+	journald.TEST_SUITE: journald.FromParams,
+}
+
+func main() {
+	flag.Parse()
+
+	// started by the multi-writer test in a writer unit.
+	if *writerRunID != "" {
+		runWriter()
+		return
+	}
+
+	if *planFile == "" {
+		log.Fatal("-plan is required")
+	}
+
+	p, err := plan.Load(*planFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *dryRun {
+		points, err := p.Expand()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, point := range points {
+			log.Infof("%s: %s, cooldown %s", point.Name, point.Params.Format(), point.Cooldown)
+		}
+		return
+	}
+
+	stages, err := p.Stages(factories)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	clientOptions := []http.ClientOption{http.ClientOptionToken(*supervisorToken)}
+	if *supervisorCA != "" {
+		clientOptions = append(clientOptions, http.ClientOptionCA(*supervisorCA))
+	}
+	if *clientCert != "" {
+		clientOptions = append(clientOptions, http.ClientOptionCertificate(*clientCert, *clientKey))
+	}
+
+	if err := http.SetDefaultClient(clientOptions...); err != nil {
+		log.Fatal(err)
+	}
+
+	testSuite, err := test.NewSuite(
+		test.OptionSupervisorURL(*supervisorURL),
+		test.OptionStages(stages...))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// interrupting the plan stops the running point and skips the rest, teardown still runs.
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-signals
+		log.Infof("Received %s, stopping tests", s)
+		cancel()
+	}()
+
+	log.Infof("Running %d plan points", len(stages))
+	result := testSuite.Start(ctx)
+
+	if err := http.DefaultClient.Close(); err != nil {
+		log.Error(err)
+		result.Passed = false
+	}
+
+	result.Log()
+	if *resultFile != "" {
+		if err := result.WriteFile(*resultFile); err != nil {
+			log.Error(err)
+		}
+	}
+
+	os.Exit(result.ExitCode())
+}
+
+// runWriter runs a multi-writer test writer for -duration seconds or until it is stopped.
+func runWriter() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*writerDuration)*time.Second)
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	if err := journald.RunWriter(ctx, *writerRunID, *writerRate, *writerLineSize, *writerResult); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unixScheme is a scheme of supervisor URLs served on a unix socket, e.g.
+// unix:///run/perf-supervisor.sock/incoming. The HTTP path defaults to /incoming.
+const unixScheme = "unix://"
+
+// Client posts events to the supervisor.
+type Client struct {
+	http  *http.Client
+	tls   *tls.Config
+	token string
+
+	mu      sync.Mutex
+	unix    map[string]*http.Client
+	streams map[string]*eventStream
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client) error
+
+// NewClient returns a new Client configured with functional options.
+func NewClient(options ...ClientOption) (*Client, error) {
+	c := &Client{
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.tls != nil {
+		c.http.Transport = &http.Transport{TLSClientConfig: c.tls}
+	}
+	return c, nil
+}
+
+func (c *Client) tlsConfig() *tls.Config {
+	if c.tls == nil {
+		c.tls = &tls.Config{}
+	}
+	return c.tls
+}
+
+// ClientOptionToken sends a bearer token with every request.
+func ClientOptionToken(token string) ClientOption {
+	return func(c *Client) error {
+		c.token = token
+		return nil
+	}
+}
+
+// ClientOptionCA verifies the supervisor certificate with a CA file.
+func ClientOptionCA(path string) ClientOption {
+	return func(c *Client) error {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Cannot read CA: %s", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("No certificates found in CA")
+		}
+		c.tlsConfig().RootCAs = pool
+		return nil
+	}
+}
+
+// ClientOptionCertificate presents a client certificate to the supervisor.
+func ClientOptionCertificate(certPath, keyPath string) ClientOption {
+	return func(c *Client) error {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return fmt.Errorf("Cannot load client certificate: %s", err)
+		}
+		c.tlsConfig().Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// ClientOptionTimeout sets a timeout for a single request.
+func ClientOptionTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.http.Timeout = timeout
+		return nil
+	}
+}
+
+// resolve returns an HTTP client and a request URL for a supervisor URL. unix:// URLs are
+// split into a socket path, the longest path prefix which is a socket, and an HTTP path.
+func (c *Client) resolve(url string) (*http.Client, string, error) {
+	if !strings.HasPrefix(url, unixScheme) {
+		return c.http, url, nil
+	}
+
+	socket, urlPath, err := splitSocketPath(strings.TrimPrefix(url, unixScheme))
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unix == nil {
+		c.unix = map[string]*http.Client{}
+	}
+
+	client, ok := c.unix[socket]
+	if !ok {
+		dialer := &net.Dialer{}
+		client = &http.Client{
+			Timeout: c.http.Timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		}
+		c.unix[socket] = client
+	}
+	return client, "http://unix" + urlPath, nil
+}
+
+func splitSocketPath(p string) (string, string, error) {
+	p = path.Clean(p)
+	for socket := p; socket != "/" && socket != "."; socket = path.Dir(socket) {
+		fi, err := os.Stat(socket)
+		if err != nil || fi.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+
+		urlPath := strings.TrimPrefix(p, socket)
+		if urlPath == "" {
+			urlPath = "/incoming"
+		}
+		return socket, urlPath, nil
+	}
+	return "", "", fmt.Errorf("No unix socket found in %s", p)
+}
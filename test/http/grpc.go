@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/supervisor/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// schemes of supervisor gRPC addresses, e.g. grpc://localhost:9124. Events sent to a gRPC
+// address share one persistent stream per address until the client is closed.
+const (
+	grpcScheme  = "grpc://"
+	grpcsScheme = "grpcs://"
+)
+
+func isGRPC(url string) bool {
+	return strings.HasPrefix(url, grpcScheme) || strings.HasPrefix(url, grpcsScheme)
+}
+
+type eventStream struct {
+	conn   *grpc.ClientConn
+	stream rpc.Supervisor_PushEventsClient
+	cancel context.CancelFunc
+}
+
+func (s *eventStream) close() (*rpc.PushEventsResponse, error) {
+	defer s.conn.Close()
+	defer s.cancel()
+	return s.stream.CloseAndRecv()
+}
+
+// pushEvents sends events over a persistent stream, the stream is opened on first use.
+func (c *Client) pushEvents(url string, events []backend.EventSchema) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.streams[url]
+	if !ok {
+		var err error
+		if s, err = c.openStream(url); err != nil {
+			return err
+		}
+
+		if c.streams == nil {
+			c.streams = map[string]*eventStream{}
+		}
+		c.streams[url] = s
+	}
+
+	for i := range events {
+		if err := s.stream.Send(rpc.NewEvent(&events[i])); err != nil {
+			delete(c.streams, url)
+
+			// the server closed the stream, the reason is returned on receive.
+			if err == io.EOF {
+				_, err = s.close()
+			} else {
+				s.close()
+			}
+			return fmt.Errorf("Error sending events to %s: %s", url, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) openStream(url string) (*eventStream, error) {
+	options := []grpc.DialOption{}
+	if strings.HasPrefix(url, grpcsScheme) {
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(c.tlsConfig())))
+	} else {
+		options = append(options, grpc.WithInsecure())
+	}
+
+	target := strings.TrimPrefix(strings.TrimPrefix(url, grpcScheme), grpcsScheme)
+	conn, err := grpc.Dial(target, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if c.token != "" {
+		ctx = metadata.NewContext(ctx, metadata.Pairs("authorization", "Bearer "+c.token))
+	}
+
+	stream, err := rpc.NewSupervisorClient(conn).PushEvents(ctx)
+	if err != nil {
+		cancel()
+		conn.Close()
+		return nil, err
+	}
+	return &eventStream{conn: conn, stream: stream, cancel: cancel}, nil
+}
+
+// Close closes persistent gRPC streams. An error is returned if a stream failed or the
+// supervisor rejected events sent over it.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := []string{}
+	for url, s := range c.streams {
+		resp, err := s.close()
+		delete(c.streams, url)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", url, err))
+			continue
+		}
+
+		logrus.Debugf("Supervisor %s accepted %d events, rejected %d", url, resp.Accepted, resp.Rejected)
+		if resp.Rejected > 0 {
+			errs = append(errs, fmt.Sprintf("%s: %d events rejected: %s", url, resp.Rejected, strings.Join(resp.Errors, "; ")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Error closing supervisor streams: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
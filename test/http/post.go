@@ -3,35 +3,97 @@ package http
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/mesosphere/performance/supervisor/backend"
 )
 
-func PostToSupervisor(url string, event backend.BigQuerySchema) error {
-	fmt.Println("Sending to %s", url)
-	json, err := json.Marshal(event)
+// DefaultClient is used by PostToSupervisor and PostEventsToSupervisor. Use
+// SetDefaultClient to configure TLS and authentication.
+var DefaultClient = &Client{http: &http.Client{}}
+
+// SetDefaultClient replaces DefaultClient with a client configured with options.
+func SetDefaultClient(options ...ClientOption) error {
+	client, err := NewClient(options...)
+	if err != nil {
+		return err
+	}
+	DefaultClient = client
+	return nil
+}
+
+// PostToSupervisor sends a single event to the supervisor. url is either an http(s):// URL,
+// a unix:// URL of a supervisor socket, e.g. unix:///run/perf-supervisor.sock, or a grpc(s)://
+// address of the supervisor gRPC server.
+func PostToSupervisor(url string, event backend.EventSchema) error {
+	return DefaultClient.PostEvents(url, []backend.EventSchema{event})
+}
+
+// PostEventsToSupervisor sends a batch of events to the supervisor in a single request.
+func PostEventsToSupervisor(url string, events []backend.EventSchema) error {
+	return DefaultClient.PostEvents(url, events)
+}
+
+// PostEvents sends a batch of events to the supervisor in a single request. Events sent to
+// a gRPC address are streamed, call Close to get the number of accepted events.
+func (c *Client) PostEvents(url string, events []backend.EventSchema) error {
+	logrus.Debugf("Sending %d events to %s", len(events), url)
+	if isGRPC(url) {
+		return c.pushEvents(url, events)
+	}
+
+	payload, err := json.Marshal(events)
 	if err != nil {
 		return err
 	}
 
-	client := http.Client{}
+	client, url, err := c.resolve(url)
+	if err != nil {
+		return err
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(json))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(fmt.Sprintf("Got %d response code from supervisor", resp.StatusCode))
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Got %d response code from supervisor: %s", resp.StatusCode, body)
 	}
 
+	// 202 carries accepted and rejected counts, events are rejected individually.
+	if resp.StatusCode == http.StatusAccepted {
+		result := ingestResponse{}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("Cannot parse supervisor response %s: %s", body, err)
+		}
+
+		if result.Rejected > 0 {
+			return fmt.Errorf("Supervisor rejected %d of %d events: %s", result.Rejected, len(events), strings.Join(result.Errors, "; "))
+		}
+	}
 	return nil
 }
+
+// ingestResponse is a response of the supervisor to a batch of events.
+type ingestResponse struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors"`
+}
@@ -1,5 +1,7 @@
 package test
 
+import "fmt"
+
 // TestOption represents a test option for the scale test
 // package
 type TestOption func(*ScaleTest) error
@@ -13,11 +15,58 @@ func OptionSupervisorURL(url string) TestOption {
 	}
 }
 
-// OptionTesters configures the test suites to run
-// during the scale test
+// OptionTesters configures the test suites to run one after another
+// during the scale test, each in its own stage
 func OptionTesters(testers ...ScaleTester) TestOption {
 	return func(s *ScaleTest) error {
-		s.Testers = testers
+		for _, tester := range testers {
+			s.Stages = append(s.Stages, Stage{Name: tester.Name(), Testers: []ScaleTester{tester}})
+		}
+		return nil
+	}
+}
+
+// OptionStage adds a stage of test suites which run concurrently,
+// after previously added stages. If cancelOnFailure is set, a failed
+// tester cancels the rest of the stage
+func OptionStage(name string, cancelOnFailure bool, testers ...ScaleTester) TestOption {
+	return func(s *ScaleTest) error {
+		if len(testers) == 0 {
+			return fmt.Errorf("Stage %s has no testers", name)
+		}
+
+		s.Stages = append(s.Stages, Stage{Name: name, Testers: testers, CancelOnFailure: cancelOnFailure})
+		return nil
+	}
+}
+
+// OptionStages adds stages to run after previously added stages
+func OptionStages(stages ...Stage) TestOption {
+	return func(s *ScaleTest) error {
+		for _, stage := range stages {
+			if len(stage.Testers) == 0 {
+				return fmt.Errorf("Stage %s has no testers", stage.Name)
+			}
+		}
+
+		s.Stages = append(s.Stages, stages...)
+		return nil
+	}
+}
+
+// OptionTimeouts configures phase timeouts for all testers
+func OptionTimeouts(timeouts Timeouts) TestOption {
+	return func(s *ScaleTest) error {
+		s.Timeouts = timeouts
+		return nil
+	}
+}
+
+// OptionTesterTimeouts configures phase timeouts for a single
+// tester by name, overriding OptionTimeouts
+func OptionTesterTimeouts(name string, timeouts Timeouts) TestOption {
+	return func(s *ScaleTest) error {
+		s.TesterTimeouts[name] = timeouts
 		return nil
 	}
 }
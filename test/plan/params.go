@@ -0,0 +1,143 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Params are parameters of a single plan point, decoded from JSON.
+type Params map[string]interface{}
+
+// Int returns an integer parameter or def if it is not set.
+func (p Params) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+
+	n, ok := v.(float64)
+	if !ok || n != float64(int(n)) {
+		return 0, fmt.Errorf("Parameter %s must be an integer, got %v", key, v)
+	}
+	return int(n), nil
+}
+
+// Ints returns a parameter given as a JSON array of integers, e.g. [100, 1000], or def if it is not set.
+func (p Params) Ints(key string, def []int) ([]int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Parameter %s must be an array of integers, got %v", key, v)
+	}
+
+	ints := []int{}
+	for _, value := range values {
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) {
+			return nil, fmt.Errorf("Parameter %s must be an array of integers, got %v", key, v)
+		}
+		ints = append(ints, int(n))
+	}
+	return ints, nil
+}
+
+// Bool returns a boolean parameter or def if it is not set.
+func (p Params) Bool(key string, def bool) (bool, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("Parameter %s must be a boolean, got %v", key, v)
+	}
+	return b, nil
+}
+
+// String returns a string parameter or def if it is not set.
+func (p Params) String(key string, def string) (string, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("Parameter %s must be a string, got %v", key, v)
+	}
+	return s, nil
+}
+
+// Duration returns a duration parameter, e.g. "30s", or def if it is not set.
+func (p Params) Duration(key string, def time.Duration) (time.Duration, error) {
+	s, err := p.String(key, "")
+	if err != nil || s == "" {
+		return def, err
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("Parameter %s: %s", key, err)
+	}
+	return d, nil
+}
+
+// Only returns an error if a parameter other than keys is set, so typos are not ignored.
+func (p Params) Only(keys ...string) error {
+	known := map[string]bool{}
+	for _, key := range keys {
+		known[key] = true
+	}
+
+	unknown := []string{}
+	for key := range p {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Unknown parameters %s, expected %s", strings.Join(unknown, ", "), strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// Labels returns all parameters as strings, to tag events of a plan point.
+func (p Params) Labels() map[string]string {
+	labels := map[string]string{}
+	for key, value := range p {
+		labels[key] = paramString(value)
+	}
+	return labels
+}
+
+// Format returns parameters as key=value pairs sorted by key.
+func (p Params) Format() string {
+	keys := []string{}
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+paramString(p[key]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func paramString(v interface{}) string {
+	if n, ok := v.(float64); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
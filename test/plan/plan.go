@@ -0,0 +1,188 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/mesosphere/performance/test"
+)
+
+// Plan is a declarative list of suites to run, loaded from a JSON file:
+//
+//	{
+//	  "cooldown": "30s",
+//	  "suites": [{
+//	    "suite": "journald",
+//	    "params": {"test_type": "constant-rate", "duration": 60},
+//	    "matrix": {"log_rate": [100, 1000, 10000], "line_size": [128, 2048], "stderr": [true, false]}
+//	  }]
+//	}
+//
+// Every combination of matrix values is a point, points run one after another with a cooldown between them.
+type Plan struct {
+	Cooldown string  `json:"cooldown"`
+	Suites   []Suite `json:"suites"`
+}
+
+// Suite is a suite with fixed parameters and a matrix of swept parameters.
+type Suite struct {
+	Suite    string                   `json:"suite"`
+	Name     string                   `json:"name"`
+	Cooldown string                   `json:"cooldown"`
+	Params   Params                   `json:"params"`
+	Matrix   map[string][]interface{} `json:"matrix"`
+}
+
+// Point is a single execution of a suite with a full parameter set.
+type Point struct {
+	Suite    string
+	Name     string
+	Params   Params
+	Cooldown time.Duration
+}
+
+// Factory returns a tester for a point. Testers should tag their events with params.Labels().
+type Factory func(name string, params Params) (test.ScaleTester, error)
+
+// Load reads a plan from a JSON file.
+func Load(path string) (*Plan, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read plan: %s", err)
+	}
+
+	p := &Plan{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, fmt.Errorf("Cannot parse plan %s: %s", path, err)
+	}
+	return p, nil
+}
+
+// Expand returns all points of the plan in order. Matrix keys are expanded in alphabetical
+// order, the last key changes fastest.
+func (p *Plan) Expand() ([]Point, error) {
+	if len(p.Suites) == 0 {
+		return nil, fmt.Errorf("Plan has no suites")
+	}
+
+	planCooldown, err := parseCooldown(p.Cooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []Point{}
+	for i, s := range p.Suites {
+		if s.Suite == "" {
+			return nil, fmt.Errorf("Suite %d: field suite is required", i)
+		}
+
+		name := s.Name
+		if name == "" {
+			name = s.Suite
+		}
+
+		cooldown := planCooldown
+		if s.Cooldown != "" {
+			if cooldown, err = parseCooldown(s.Cooldown); err != nil {
+				return nil, fmt.Errorf("Suite %s: %s", name, err)
+			}
+		}
+
+		keys := []string{}
+		for key, values := range s.Matrix {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("Suite %s: matrix parameter %s has no values", name, key)
+			}
+			if _, ok := s.Params[key]; ok {
+				return nil, fmt.Errorf("Suite %s: parameter %s is set in both params and matrix", name, key)
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, swept := range combinations(keys, s.Matrix) {
+			params := Params{}
+			for key, value := range s.Params {
+				params[key] = value
+			}
+			for key, value := range swept {
+				params[key] = value
+			}
+
+			pointName := name
+			if len(swept) > 0 {
+				pointName = fmt.Sprintf("%s[%s]", name, swept.Format())
+			}
+
+			points = append(points, Point{
+				Suite:    s.Suite,
+				Name:     pointName,
+				Params:   params,
+				Cooldown: cooldown,
+			})
+		}
+	}
+	return points, nil
+}
+
+// combinations returns the cartesian product of matrix values, a single empty set for an empty matrix.
+func combinations(keys []string, matrix map[string][]interface{}) []Params {
+	result := []Params{{}}
+	for _, key := range keys {
+		next := []Params{}
+		for _, partial := range result {
+			for _, value := range matrix[key] {
+				params := Params{key: value}
+				for k, v := range partial {
+					params[k] = v
+				}
+				next = append(next, params)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+func parseCooldown(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid cooldown %s", s)
+	}
+	return d, nil
+}
+
+// Stages returns a stage per point, created by a factory registered for the point suite.
+func (p *Plan) Stages(factories map[string]Factory) ([]test.Stage, error) {
+	points, err := p.Expand()
+	if err != nil {
+		return nil, err
+	}
+
+	stages := []test.Stage{}
+	for _, point := range points {
+		factory, ok := factories[point.Suite]
+		if !ok {
+			return nil, fmt.Errorf("Unknown suite %s", point.Suite)
+		}
+
+		tester, err := factory(point.Name, point.Params)
+		if err != nil {
+			return nil, fmt.Errorf("Point %s: %s", point.Name, err)
+		}
+
+		stages = append(stages, test.Stage{
+			Name:     point.Name,
+			Testers:  []test.ScaleTester{tester},
+			Cooldown: point.Cooldown,
+		})
+	}
+	return stages, nil
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Result is an outcome of a single tester. A result passes unless an error is added.
+type Result struct {
+	Tester      string             `json:"tester"`
+	Stage       string             `json:"stage"`
+	Passed      bool               `json:"passed"`
+	Started     time.Time          `json:"started"`
+	DurationSec float64            `json:"duration_sec"`
+	Metrics     map[string]float64 `json:"metrics,omitempty"`
+	Errors      []string           `json:"errors,omitempty"`
+}
+
+// NewResult returns a new passing Result.
+func NewResult(tester string) *Result {
+	return &Result{
+		Tester:  tester,
+		Passed:  true,
+		Started: time.Now(),
+		Metrics: map[string]float64{},
+	}
+}
+
+// AddError fails the result. nil errors are ignored.
+func (r *Result) AddError(err error) {
+	if err == nil {
+		return
+	}
+	r.Passed = false
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// SetMetric sets a named metric, e.g. lines_written.
+func (r *Result) SetMetric(name string, value float64) {
+	if r.Metrics == nil {
+		r.Metrics = map[string]float64{}
+	}
+	r.Metrics[name] = value
+}
+
+// SuiteResult aggregates results of all testers.
+type SuiteResult struct {
+	Passed  bool      `json:"passed"`
+	Results []*Result `json:"results"`
+}
+
+func (s *SuiteResult) add(r *Result) {
+	s.Results = append(s.Results, r)
+	if !r.Passed {
+		s.Passed = false
+	}
+}
+
+// ExitCode returns a process exit code, non-zero if any tester failed.
+func (s *SuiteResult) ExitCode() int {
+	if s.Passed {
+		return 0
+	}
+	return 1
+}
+
+// Log logs a summary line per tester.
+func (s *SuiteResult) Log() {
+	for _, r := range s.Results {
+		entry := slog.WithField("tester", r.Tester)
+		if r.Passed {
+			entry.Infof("PASSED in %.1fs, metrics %v", r.DurationSec, r.Metrics)
+			continue
+		}
+		entry.Errorf("FAILED in %.1fs, metrics %v, errors %v", r.DurationSec, r.Metrics, r.Errors)
+	}
+}
+
+// WriteFile writes the result as JSON.
+func (s *SuiteResult) WriteFile(path string) error {
+	body, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("Cannot write result file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package journald
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/mesosphere/performance/test/http"
+	"github.com/shirou/gopsutil/process"
+)
+
+// dropped logs event test and action.
+const (
+	DROPPED_LOGS = "dropped-logs"
+	SUPPRESSED   = "suppressed"
+)
+
+// journaldDroppedMessageID is the MESSAGE_ID of journald messages about suppressed
+// messages, SD_MESSAGE_JOURNAL_DROPPED in systemd.
+const journaldDroppedMessageID = "fe6faa94e7774663a0da52717891d8ef"
+
+// suppressedRegexp matches journald messages like
+// "Suppressed 1234 messages from /system.slice/foo.service".
+var suppressedRegexp = regexp.MustCompile(`Suppressed (\d+) messages from (\S+)`)
+
+// suppression is a single journald rate limiting report.
+type suppression struct {
+	Count int
+	Unit  string
+	// Time is when journald reported the suppression, at the end of its rate limit window.
+	Time time.Time
+}
+
+// parseSuppression returns a suppression from journald message fields, ok is false if the
+// entry is not a suppression message. The count is taken from N_DROPPED if journald sets it.
+func parseSuppression(fields map[string]string) (s suppression, ok bool) {
+	match := suppressedRegexp.FindStringSubmatch(fields[sdjournal.SD_JOURNAL_FIELD_MESSAGE])
+	if match == nil {
+		return s, false
+	}
+
+	count := match[1]
+	if dropped, ok := fields["N_DROPPED"]; ok {
+		count = dropped
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return s, false
+	}
+
+	// the unit is reported as a cgroup path, e.g. /system.slice/foo.service.
+	return suppression{Count: n, Unit: path.Base(match[2])}, true
+}
+
+// journaldPID returns the PID of systemd-journald, 0 if it is not found.
+func journaldPID() int32 {
+	pids, err := process.Pids()
+	if err != nil {
+		return 0
+	}
+
+	for _, pid := range pids {
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			continue
+		}
+
+		// process names are truncated to 15 characters.
+		if name, err := p.Name(); err == nil && (name == "systemd-journal" || name == "systemd-journald") {
+			return pid
+		}
+	}
+	return 0
+}
+
+// newSuppressionReader returns a journal positioned at its tail, matching messages of
+// systemd-journald itself or with the dropped messages MESSAGE_ID.
+func newSuppressionReader() (*sdjournal.Journal, error) {
+	reader, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open journal: %s", err)
+	}
+
+	matches := []string{sdjournal.SD_JOURNAL_FIELD_MESSAGE_ID + "=" + journaldDroppedMessageID}
+	if pid := journaldPID(); pid != 0 {
+		matches = append(matches, fmt.Sprintf("%s=%d", sdjournal.SD_JOURNAL_FIELD_PID, pid))
+	}
+
+	for i, match := range matches {
+		if i > 0 {
+			if err := reader.AddDisjunction(); err != nil {
+				reader.Close()
+				return nil, fmt.Errorf("Cannot add journal match: %s", err)
+			}
+		}
+
+		if err := reader.AddMatch(match); err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("Cannot add journal match: %s", err)
+		}
+	}
+
+	if err := reader.SeekTail(); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("Cannot seek journal tail: %s", err)
+	}
+	// SeekTail points after the last entry, step back so that Next returns new entries only.
+	reader.Previous()
+
+	return reader, nil
+}
+
+// currentUnit returns the systemd unit of this process, e.g. foo.service or session-1.scope,
+// from its systemd cgroup.
+func currentUnit() (string, error) {
+	body, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("Cannot read process cgroup: %s", err)
+	}
+
+	// lines are hierarchy-ID:controllers:path, the systemd hierarchy is named on cgroup v1
+	// and the unified one has ID 0 and no controllers.
+	cgroup := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[1] == "name=systemd" {
+			cgroup = fields[2]
+			break
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			cgroup = fields[2]
+		}
+	}
+
+	// units may delegate sub-cgroups, the unit is the innermost service or scope.
+	parts := strings.Split(cgroup, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if strings.HasSuffix(parts[i], ".service") || strings.HasSuffix(parts[i], ".scope") {
+			return parts[i], nil
+		}
+	}
+	return "", fmt.Errorf("Process cgroup %q is not in a systemd unit", cgroup)
+}
+
+// droppedLogsDetector follows the journal from its tail until ctx is done and is meant to be
+// ran in asynchronous goroutine. Each journald suppression message is sent to the supervisor
+// as a dropped-logs event carrying the suppressed count and unit, labeled whether the unit is
+// one of units of the test. Only suppressions of units are passed to onDrop if it is not nil,
+// other services on the host are rate limited independently of the test.
+func droppedLogsDetector(ctx context.Context, supervisorURL string, j JournaldTestSuite, units []string, onDrop func(suppression)) error {
+	jlog.Info("Starting dropped logs detection service")
+
+	reader, err := newSuppressionReader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for ctx.Err() == nil {
+		n, err := reader.Next()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			reader.Wait(time.Second)
+			continue
+		}
+
+		entry, err := reader.GetEntry()
+		if err != nil {
+			return err
+		}
+
+		s, ok := parseSuppression(entry.Fields)
+		if !ok {
+			continue
+		}
+		s.Time = time.Unix(0, int64(entry.RealtimeTimestamp)*int64(time.Microsecond))
+
+		testUnit := false
+		for _, unit := range units {
+			testUnit = testUnit || unit == s.Unit
+		}
+
+		jlog.Warnf("Journald suppressed %d messages from %s", s.Count, s.Unit)
+		dropEvent, err := j.newEvent(DROPPED_LOGS, SUPPRESSED, float64(s.Count), LINES)
+		if err != nil {
+			return err
+		}
+		dropEvent.SetLabel("unit", s.Unit)
+		dropEvent.SetLabel("test_unit", strconv.FormatBool(testUnit))
+
+		if err := http.PostToSupervisor(supervisorURL, dropEvent); err != nil {
+			return err
+		}
+
+		if onDrop != nil && testUnit {
+			onDrop(s)
+		}
+	}
+
+	jlog.Info("Canceling dropped log detector routine")
+	return nil
+}
+
+// runDetector runs droppedLogsDetector for units in a goroutine until ctx is done. If the detector fails,
+// stop is called so that the test does not go on without detection. The returned channel
+// receives the detector error, nil on success, when it exits.
+func runDetector(ctx context.Context, stop context.CancelFunc, supervisorURL string, j JournaldTestSuite, units []string, onDrop func(suppression)) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		err := droppedLogsDetector(ctx, supervisorURL, j, units, onDrop)
+		if err != nil {
+			jlog.Errorf("Dropped logs detector failed, stopping test: %s", err)
+			stop()
+			err = fmt.Errorf("Dropped logs detector failed: %s", err)
+		}
+		done <- err
+	}()
+	return done
+}
+
+// testUnits returns the unit of the test process, none if it is not known.
+func (j JournaldTestSuite) testUnits() []string {
+	if j.unit == "" {
+		return nil
+	}
+	return []string{j.unit}
+}
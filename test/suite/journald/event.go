@@ -1,12 +1,24 @@
 package journald
 
 import (
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/mesosphere/performance/supervisor/backend"
 	"github.com/shirou/gopsutil/host"
 )
 
-func newEvent(name string) (backend.BigQuerySchema, error) {
-	event := backend.BigQuerySchema{}
+// newEvent returns a journald suite event tagged with the suite labels. value and unit are
+// optional and omitted from the event name when unit is empty.
+func (j JournaldTestSuite) newEvent(test, action string, value float64, unit string) (backend.EventSchema, error) {
+	event := backend.EventSchema{
+		Suite:  TEST_SUITE,
+		Test:   test,
+		Action: action,
+		Value:  value,
+		Unit:   unit,
+	}
 
 	hostname, err := getHostname()
 	if err != nil {
@@ -20,7 +32,23 @@ func newEvent(name string) (backend.BigQuerySchema, error) {
 	}
 	event.Instance = taskID
 
-	event.Name = name
+	parts := []string{TEST_SUITE, test}
+	if action != "" {
+		parts = append(parts, action)
+	}
+	if unit != "" {
+		parts = append(parts, strconv.FormatFloat(value, 'f', -1, 64), unit)
+	}
+	event.Name = strings.Join(parts, DELIMITER)
+
+	keys := []string{}
+	for key := range j.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		event.SetLabel(key, j.Labels[key])
+	}
 
 	return event, nil
 }
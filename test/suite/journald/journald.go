@@ -2,65 +2,493 @@ package journald
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/coreos/go-systemd/dbus"
+	"github.com/coreos/go-systemd/journal"
 	"github.com/mesosphere/performance/supervisor/backend"
+	"github.com/mesosphere/performance/test"
+	"github.com/mesosphere/performance/test/plan"
 )
 
 /*
-Use a basic naming convention for Name parameter which is
-used as event name:
+Events carry structured fields, their Name parameter keeps a basic
+naming convention for readability:
+
 	TEST_SUITE::TEST_NAME::ACTION::VALUE::UNIT
 */
 const (
 	TEST_SUITE       = "journald"
 	START            = "start"
 	STOP             = "stop"
+	STEP             = "step"
+	SATURATED        = "saturated"
 	LINES_PER_SECOND = "lines/second"
-	DELIMITER        = "::"
+	LINES            = "lines"
+	WRITERS          = "writers"
+	WRITTEN          = "written"
+	DELIMITER        = backend.EventNameDelimiter
 )
 
 var jlog = logrus.WithFields(logrus.Fields{
 	"suite": "journald",
 })
 
+// test types.
+const (
+	CONSTANT_RATE = "constant-rate"
+	RAMP          = "ramp"
+	BURST         = "burst"
+	MULTI_WRITER  = "multi-writer"
+)
+
+// ramp curves.
+const (
+	RAMP_LINEAR      = "linear"
+	RAMP_EXPONENTIAL = "exponential"
+)
+
+// defaults of optional test parameters.
+const (
+	DefaultLineSize      = 2048
+	DefaultRampStartRate = 100
+	DefaultRampSteps     = 10
+	DefaultBurstSize     = 1000
+	DefaultBurstGap      = 10 * time.Second
+	DefaultWriters       = 4
+
+	// DefaultRateLimitInterval is the journald RateLimitIntervalSec default.
+	DefaultRateLimitInterval = 30 * time.Second
+)
+
 // JournaldTestSuite object implements scale.Tester for journald scale testing
 type JournaldTestSuite struct {
 	LoggingRate  int
 	StdErr       bool
 	TestDuration int
 	TestType     string
-	EventChan    chan backend.BigQuerySchema
-	Context      context.Context
+	LineSize     int
+	TesterName   string
+	Labels       map[string]string
+	EventChan    chan backend.EventSchema
+
+	// ramp test parameters, the ramp ends at LoggingRate. RateLimitInterval is the journald
+	// RateLimitIntervalSec, journald reports suppressed lines only when a rate limit window ends.
+	RampStartRate     int
+	RampSteps         int
+	RampCurve         string
+	RateLimitInterval time.Duration
+
+	// burst test parameters.
+	BurstSize int
+	BurstGap  time.Duration
+
+	// Verify reads written lines back from the journal and reports delivery and latency,
+	// VerifyGrace is how long to wait for lines after the test stops writing.
+	Verify      bool
+	VerifyGrace time.Duration
+
+	// multi-writer test parameters. WriterRates are repeated if there are more writers than
+	// rates, all writers log at LoggingRate if it is empty. WriterCommand is run for each writer
+	// in the unit mode with -writer-run-id, -writer-result, -log-rate, -line-size and -duration
+	// and must call RunWriter, as journald-scale-test does.
+	Writers       int
+	WriterRates   []int
+	WriterMode    string
+	WriterCommand string
+
+	lines *lineSource
+	// systemd unit of the test process, empty if it is not known.
+	unit string
+}
+
+// Option configures a JournaldTestSuite.
+type Option func(*JournaldTestSuite) error
+
+// OptionLineSize sets the size of log lines in bytes, including the newline.
+func OptionLineSize(size int) Option {
+	return func(j *JournaldTestSuite) error {
+		if size < 1 {
+			return fmt.Errorf("Invalid line size %d", size)
+		}
+		j.LineSize = size
+		return nil
+	}
+}
+
+// OptionRamp configures the ramp test to start at startRate lines per second and reach
+// the logging rate in steps steps, following a linear or exponential curve.
+func OptionRamp(startRate, steps int, curve string) Option {
+	return func(j *JournaldTestSuite) error {
+		if startRate <= 0 {
+			return fmt.Errorf("Invalid ramp start rate %d", startRate)
+		}
+
+		if steps <= 0 {
+			return fmt.Errorf("Invalid ramp steps %d", steps)
+		}
+
+		if curve != RAMP_LINEAR && curve != RAMP_EXPONENTIAL {
+			return fmt.Errorf("Invalid ramp curve %s, expected %s or %s", curve, RAMP_LINEAR, RAMP_EXPONENTIAL)
+		}
+
+		j.RampStartRate = startRate
+		j.RampSteps = steps
+		j.RampCurve = curve
+		return nil
+	}
+}
+
+// OptionRateLimitInterval sets the journald RateLimitIntervalSec, so that the ramp test attributes
+// suppressed lines to the step which was active when their rate limit window began.
+func OptionRateLimitInterval(interval time.Duration) Option {
+	return func(j *JournaldTestSuite) error {
+		if interval <= 0 {
+			return fmt.Errorf("Invalid rate limit interval %s", interval)
+		}
+		j.RateLimitInterval = interval
+		return nil
+	}
+}
+
+// OptionBurst configures the burst test to write bursts of size lines separated by gap.
+func OptionBurst(size int, gap time.Duration) Option {
+	return func(j *JournaldTestSuite) error {
+		if size <= 0 {
+			return fmt.Errorf("Invalid burst size %d", size)
+		}
+
+		if gap < 0 {
+			return fmt.Errorf("Invalid burst gap %s", gap)
+		}
+
+		j.BurstSize = size
+		j.BurstGap = gap
+		return nil
+	}
+}
+
+// OptionVerify enables delivery verification, lines are read back from the journal for
+// grace after the test stops writing.
+func OptionVerify(verify bool, grace time.Duration) Option {
+	return func(j *JournaldTestSuite) error {
+		if grace < 0 {
+			return fmt.Errorf("Invalid verify grace %s", grace)
+		}
+
+		j.Verify = verify
+		j.VerifyGrace = grace
+		return nil
+	}
+}
+
+// OptionWriters configures the multi-writer test to run writers writers with rates in
+// the goroutine or unit mode.
+func OptionWriters(writers int, rates []int, mode string) Option {
+	return func(j *JournaldTestSuite) error {
+		if writers <= 0 {
+			return fmt.Errorf("Invalid number of writers %d", writers)
+		}
+
+		for _, rate := range rates {
+			if rate <= 0 {
+				return fmt.Errorf("Invalid writer rate %d", rate)
+			}
+		}
+
+		if mode != WRITER_GOROUTINE && mode != WRITER_UNIT {
+			return fmt.Errorf("Invalid writer mode %s, expected %s or %s", mode, WRITER_GOROUTINE, WRITER_UNIT)
+		}
+
+		j.Writers = writers
+		j.WriterRates = rates
+		j.WriterMode = mode
+		return nil
+	}
+}
+
+// OptionWriterCommand sets the command run by writer units, the current executable by default.
+func OptionWriterCommand(command string) Option {
+	return func(j *JournaldTestSuite) error {
+		j.WriterCommand = command
+		return nil
+	}
+}
+
+// OptionName sets the tester name, used in results and per tester timeouts.
+func OptionName(name string) Option {
+	return func(j *JournaldTestSuite) error {
+		j.TesterName = name
+		return nil
+	}
+}
+
+// OptionLabels adds labels to every event the suite sends.
+func OptionLabels(labels map[string]string) Option {
+	return func(j *JournaldTestSuite) error {
+		for key, value := range labels {
+			j.Labels[key] = value
+		}
+		return nil
+	}
 }
 
 // NewTestSuite returns a valid JournalTestSuite object that implements a scale.Tester
-func NewTestSuite(logRate, testDuration int, testType string, stdErr bool) (JournaldTestSuite, error) {
-	ctx, _ := context.WithTimeout(context.Background(), time.Duration(testDuration)*time.Second)
+func NewTestSuite(logRate, testDuration int, testType string, stdErr bool, options ...Option) (JournaldTestSuite, error) {
+	if logRate <= 0 {
+		return JournaldTestSuite{}, fmt.Errorf("Invalid log rate %d", logRate)
+	}
+
+	if testDuration <= 0 {
+		return JournaldTestSuite{}, fmt.Errorf("Invalid test duration %d", testDuration)
+	}
 
-	return JournaldTestSuite{
+	j := JournaldTestSuite{
 		LoggingRate:  logRate,
-		EventChan:    make(chan backend.BigQuerySchema),
+		EventChan:    make(chan backend.EventSchema),
 		StdErr:       stdErr,
 		TestDuration: testDuration,
 		TestType:     testType,
-		Context:      ctx,
-	}, nil
+		LineSize:     DefaultLineSize,
+		Labels:       map[string]string{},
+
+		RampStartRate:     DefaultRampStartRate,
+		RampSteps:         DefaultRampSteps,
+		RampCurve:         RAMP_LINEAR,
+		RateLimitInterval: DefaultRateLimitInterval,
+
+		BurstSize: DefaultBurstSize,
+		BurstGap:  DefaultBurstGap,
+
+		VerifyGrace: DefaultVerifyGrace,
+
+		Writers:       DefaultWriters,
+		WriterMode:    WRITER_GOROUTINE,
+		WriterCommand: os.Args[0],
+	}
+
+	if executable, err := os.Executable(); err == nil {
+		j.WriterCommand = executable
+	}
+
+	for _, option := range options {
+		if err := option(&j); err != nil {
+			return JournaldTestSuite{}, err
+		}
+	}
+	return j, nil
 }
 
-func (j JournaldTestSuite) Run(supervisorURL string) error {
-	if j.TestType == "constant-rate" {
-		ConstantRate(supervisorURL, j)
+// FromParams returns a journald suite for a test plan point. Parameters are test_type,
+// log_rate, duration in seconds, stderr, line_size, ramp_start_rate, ramp_steps, ramp_curve,
+// rate_limit_interval, burst_size, burst_gap, verify, verify_grace, writers, writer_rates as an
+// array, e.g. [100, 1000], writer_mode and writer_command, all of them are added as event labels.
+func FromParams(name string, params plan.Params) (test.ScaleTester, error) {
+	err := params.Only("test_type", "log_rate", "duration", "stderr", "line_size",
+		"ramp_start_rate", "ramp_steps", "ramp_curve", "rate_limit_interval", "burst_size", "burst_gap", "verify", "verify_grace",
+		"writers", "writer_rates", "writer_mode", "writer_command")
+	if err != nil {
+		return nil, err
+	}
+
+	testType, err := params.String("test_type", CONSTANT_RATE)
+	if err != nil {
+		return nil, err
+	}
+
+	logRate, err := params.Int("log_rate", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := params.Int("duration", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	stdErr, err := params.Bool("stderr", false)
+	if err != nil {
+		return nil, err
+	}
+
+	lineSize, err := params.Int("line_size", DefaultLineSize)
+	if err != nil {
+		return nil, err
+	}
+
+	rampStartRate, err := params.Int("ramp_start_rate", DefaultRampStartRate)
+	if err != nil {
+		return nil, err
+	}
+
+	rampSteps, err := params.Int("ramp_steps", DefaultRampSteps)
+	if err != nil {
+		return nil, err
+	}
+
+	rampCurve, err := params.String("ramp_curve", RAMP_LINEAR)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitInterval, err := params.Duration("rate_limit_interval", DefaultRateLimitInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	burstSize, err := params.Int("burst_size", DefaultBurstSize)
+	if err != nil {
+		return nil, err
+	}
+
+	burstGap, err := params.Duration("burst_gap", DefaultBurstGap)
+	if err != nil {
+		return nil, err
+	}
+
+	verify, err := params.Bool("verify", false)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyGrace, err := params.Duration("verify_grace", DefaultVerifyGrace)
+	if err != nil {
+		return nil, err
+	}
+
+	writers, err := params.Int("writers", DefaultWriters)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := params.Ints("writer_rates", nil)
+	if err != nil {
+		return nil, err
 	}
 
+	writerMode, err := params.String("writer_mode", WRITER_GOROUTINE)
+	if err != nil {
+		return nil, err
+	}
+
+	writerCommand, err := params.String("writer_command", "")
+	if err != nil {
+		return nil, err
+	}
+
+	options := []Option{
+		OptionName(name),
+		OptionLineSize(lineSize),
+		OptionRamp(rampStartRate, rampSteps, rampCurve),
+		OptionRateLimitInterval(rateLimitInterval),
+		OptionBurst(burstSize, burstGap),
+		OptionVerify(verify, verifyGrace),
+		OptionWriters(writers, rates, writerMode),
+		OptionLabels(params.Labels()),
+	}
+
+	// the current executable runs writers by default.
+	if writerCommand != "" {
+		options = append(options, OptionWriterCommand(writerCommand))
+	}
+	return NewTestSuite(logRate, duration, testType, stdErr, options...)
+}
+
+func (j JournaldTestSuite) Name() string {
+	if j.TesterName != "" {
+		return j.TesterName
+	}
+	return TEST_SUITE
+}
+
+// Setup checks journald is available and the test type is known.
+func (j JournaldTestSuite) Setup(ctx context.Context, supervisorURL string) error {
+	switch j.TestType {
+	case CONSTANT_RATE, BURST:
+	case MULTI_WRITER:
+		if j.WriterMode == WRITER_UNIT {
+			if err := checkWriterCommand(ctx, j.WriterCommand); err != nil {
+				return err
+			}
+
+			conn, err := dbus.New()
+			if err != nil {
+				return fmt.Errorf("Cannot connect to systemd to start writer units: %s", err)
+			}
+			conn.Close()
+		}
+	case RAMP:
+		if j.RampStartRate > j.LoggingRate {
+			return fmt.Errorf("Ramp start rate %d is above the log rate %d", j.RampStartRate, j.LoggingRate)
+		}
+	default:
+		return fmt.Errorf("Unknown test type %s", j.TestType)
+	}
+
+	if !journal.Enabled() {
+		return errors.New("Systemd-journald not enabled, canceling request to start test")
+	}
 	return nil
 }
 
-func (j JournaldTestSuite) GetEvent() chan backend.BigQuerySchema {
-	return j.EventChan
+// Run runs the test for j.TestDuration seconds or until ctx is done.
+func (j JournaldTestSuite) Run(ctx context.Context, supervisorURL string) *test.Result {
+	result := test.NewResult(j.Name())
+
+	lines, err := newLineSource(j.LineSize)
+	if err != nil {
+		result.AddError(err)
+		return result
+	}
+	j.lines = lines
+
+	// suppressions of other units on the host are not attributed to the test.
+	if j.unit, err = currentUnit(); err != nil {
+		jlog.Warnf("Suppressed lines cannot be attributed to the test: %s", err)
+	}
+
+	var v *verifier
+	// the multi-writer test verifies delivery of each writer itself.
+	if j.Verify && j.TestType != MULTI_WRITER {
+		if v, err = startVerifier([]string{pidMatch()}, lines.runID); err != nil {
+			result.AddError(err)
+			return result
+		}
+		jlog.Infof("Verifying delivery of run %s", lines.runID)
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx, time.Duration(j.TestDuration)*time.Second)
+	defer cancel()
+
+	switch j.TestType {
+	case RAMP:
+		result.AddError(Ramp(testCtx, supervisorURL, j, result))
+	case BURST:
+		result.AddError(Burst(testCtx, supervisorURL, j, result))
+	case MULTI_WRITER:
+		result.AddError(MultiWriter(testCtx, supervisorURL, j, result))
+	default:
+		result.AddError(ConstantRate(testCtx, supervisorURL, j, result))
+	}
+
+	if v != nil {
+		if err := v.finish(ctx, j.VerifyGrace); err != nil {
+			result.AddError(fmt.Errorf("Cannot read journal: %s", err))
+		}
+		result.AddError(v.report(supervisorURL, j, result))
+	}
+	return result
 }
 
-func (j JournaldTestSuite) GetContext() context.Context {
-	return j.Context
+// Teardown is a no-op, the test does not leave anything behind.
+func (j JournaldTestSuite) Teardown(ctx context.Context) error {
+	return nil
+}
+
+func (j JournaldTestSuite) GetEvent() chan backend.EventSchema {
+	return j.EventChan
 }
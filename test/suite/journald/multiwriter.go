@@ -0,0 +1,425 @@
+package journald
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/dbus"
+	"github.com/coreos/go-systemd/journal"
+	"github.com/coreos/go-systemd/sdjournal"
+	godbus "github.com/godbus/dbus"
+	"github.com/mesosphere/performance/test"
+	"github.com/mesosphere/performance/test/http"
+)
+
+// writer modes of the multi-writer test.
+const (
+	WRITER_GOROUTINE = "goroutine"
+	WRITER_UNIT      = "unit"
+)
+
+// STREAM_JOURNAL tags lines sent to the journal directly by goroutine writers.
+const STREAM_JOURNAL = "journal"
+
+// flags a WriterCommand is called with in the unit mode, besides -log-rate, -line-size and -duration.
+const (
+	WriterRunIDFlag  = "writer-run-id"
+	WriterResultFlag = "writer-result"
+)
+
+// writerStopTimeout is how long writer units may run after the test duration before they are stopped.
+const writerStopTimeout = 10 * time.Second
+
+// writer is a single writer of the multi-writer test.
+type writer struct {
+	name       string
+	unit       string
+	rate       int
+	lines      *lineSource
+	resultFile string
+
+	written    uint64
+	suppressed int
+}
+
+// MultiWriter runs j.Writers concurrent writers, each at its own rate from j.WriterRates, until
+// ctx is done. Goroutine writers send lines to the journal directly with their own
+// SYSLOG_IDENTIFIER. Unit writers are child processes writing to STDOUT, each in its own
+// transient systemd unit, so journald rate limits them separately. Lines written, delivered and
+// suppressed are reported per writer.
+func MultiWriter(ctx context.Context, supervisorURL string, j JournaldTestSuite, result *test.Result) error {
+	jlog.Info("Starting multi-writer test for journald")
+
+	if !journal.Enabled() {
+		return errors.New("Systemd-journald not enabled, canceling request to start test")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "journald-writers")
+	if err != nil {
+		return fmt.Errorf("Cannot create writers directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writers := []*writer{}
+	matches := []string{}
+	runIDs := []string{}
+	for i := 0; i < j.Writers; i++ {
+		lines, err := newLineSource(j.LineSize)
+		if err != nil {
+			return err
+		}
+
+		w := &writer{
+			name:       fmt.Sprintf("%s-writer-%d", TEST_SUITE, i),
+			rate:       j.writerRate(i),
+			lines:      lines,
+			resultFile: filepath.Join(tmpDir, strconv.Itoa(i)),
+		}
+
+		if j.WriterMode == WRITER_UNIT {
+			w.unit = fmt.Sprintf("%s-%s.service", w.name, lines.runID)
+			matches = append(matches, sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT+"="+w.unit)
+		}
+
+		writers = append(writers, w)
+		runIDs = append(runIDs, lines.runID)
+	}
+
+	if j.WriterMode != WRITER_UNIT {
+		matches = []string{pidMatch()}
+	}
+
+	v, err := startVerifier(matches, runIDs...)
+	if err != nil {
+		return err
+	}
+
+	// suppressions are attributed to writers by unit, total counts all of them. Goroutine
+	// writers share the unit of the test.
+	units := j.testUnits()
+	if j.WriterMode == WRITER_UNIT {
+		units = nil
+		for _, w := range writers {
+			units = append(units, w.unit)
+		}
+	}
+
+	mu := &sync.Mutex{}
+	suppressed := 0
+	onDrop := func(s suppression) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		suppressed += s.Count
+		for _, w := range writers {
+			if w.unit != "" && w.unit == s.Unit {
+				w.suppressed += s.Count
+			}
+		}
+	}
+
+	// the detector keeps running until the verifier has caught up, writers stop early if it fails.
+	writeCtx, stopWriters := context.WithCancel(ctx)
+	defer stopWriters()
+	detectorCtx, stopDetector := context.WithCancel(context.Background())
+	defer stopDetector()
+	detectorErr := runDetector(detectorCtx, stopWriters, supervisorURL, j, units, onDrop)
+
+	startEvent, err := j.newEvent(MULTI_WRITER, START, float64(len(writers)), WRITERS)
+	if err != nil {
+		return err
+	}
+
+	if err := http.PostToSupervisor(supervisorURL, startEvent); err != nil {
+		return err
+	}
+
+	jlog.Infof("Starting %d %s writers:\n   Duration %d seconds\n   Line size: %d bytes", len(writers), j.WriterMode, j.TestDuration, j.LineSize)
+
+	var writeErr error
+	if j.WriterMode == WRITER_UNIT {
+		writeErr = runWriterUnits(writeCtx, j, writers)
+	} else {
+		runWriterGoroutines(writeCtx, writers)
+	}
+
+	if err := v.finish(context.Background(), j.VerifyGrace); err != nil {
+		result.AddError(fmt.Errorf("Cannot read journal: %s", err))
+	}
+	stopDetector()
+	result.AddError(<-detectorErr)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := reportWriters(supervisorURL, j, v, writers, result); err != nil {
+		return err
+	}
+	result.SetMetric("lines_suppressed", float64(suppressed))
+
+	if ctx.Err() == context.Canceled {
+		result.AddError(errors.New("Test interrupted"))
+	}
+
+	stopEvent, err := j.newEvent(MULTI_WRITER, STOP, float64(len(writers)), WRITERS)
+	if err != nil {
+		return err
+	}
+
+	if err := http.PostToSupervisor(supervisorURL, stopEvent); err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// ParseRates parses a comma separated list of rates, e.g. "100,1000,5000".
+func ParseRates(s string) ([]int, error) {
+	rates := []int{}
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+
+		rate, err := strconv.Atoi(field)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("Invalid rate %s", field)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// writerRate returns a rate of the i-th writer, WriterRates are repeated if there are more
+// writers than rates.
+func (j JournaldTestSuite) writerRate(i int) int {
+	if len(j.WriterRates) == 0 {
+		return j.LoggingRate
+	}
+	return j.WriterRates[i%len(j.WriterRates)]
+}
+
+// runWriterGoroutines sends lines of each writer to the journal in its own goroutine until ctx is done.
+func runWriterGoroutines(ctx context.Context, writers []*writer) {
+	wg := &sync.WaitGroup{}
+	for _, w := range writers {
+		wg.Add(1)
+		go func(w *writer) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(time.Second / time.Duration(w.rate))
+			defer ticker.Stop()
+
+			vars := map[string]string{"SYSLOG_IDENTIFIER": w.name}
+			for {
+				select {
+				case <-ticker.C:
+					w.lines.next()
+					if err := journal.Send(w.lines.line(STREAM_JOURNAL), journal.PriInfo, vars); err != nil {
+						jlog.Errorf("Writer %s: %s", w.name, err)
+					}
+
+				case <-ctx.Done():
+					w.written = w.lines.sent()
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// runWriterUnits starts a transient unit per writer and waits for all of them to exit. If ctx
+// is canceled the units are stopped right away, writers store their written lines on stop.
+// Units still running writerStopTimeout after ctx is done are left to the deferred stop.
+func runWriterUnits(ctx context.Context, j JournaldTestSuite, writers []*writer) error {
+	conn, err := dbus.New()
+	if err != nil {
+		return fmt.Errorf("Cannot connect to systemd: %s", err)
+	}
+	defer conn.Close()
+
+	started := []*writer{}
+	defer func() {
+		for _, w := range started {
+			conn.StopUnit(w.unit, "replace", nil)
+			conn.ResetFailedUnit(w.unit)
+		}
+	}()
+
+	for _, w := range writers {
+		if err := startWriterUnit(conn, j, w); err != nil {
+			return err
+		}
+		started = append(started, w)
+	}
+
+	<-ctx.Done()
+	if ctx.Err() == context.Canceled {
+		for _, w := range started {
+			conn.StopUnit(w.unit, "replace", nil)
+		}
+	}
+
+	deadline := time.Now().Add(writerStopTimeout)
+	for time.Now().Before(deadline) && unitsActive(conn, writers) {
+		time.Sleep(time.Second)
+	}
+
+	errs := []string{}
+	for _, w := range writers {
+		body, err := ioutil.ReadFile(w.resultFile)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("writer %s did not report written lines", w.name))
+			continue
+		}
+
+		if w.written, err = strconv.ParseUint(strings.TrimSpace(string(body)), 10, 64); err != nil {
+			errs = append(errs, fmt.Sprintf("writer %s: %s", w.name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// checkWriterCommand returns an error if command does not accept the writer flags, which are
+// looked up in its -h usage.
+func checkWriterCommand(ctx context.Context, command string) error {
+	// flag packages exit with a non-zero code after printing the usage, only the output matters.
+	out, err := exec.CommandContext(ctx, command, "-h").CombinedOutput()
+	if len(out) == 0 && err != nil {
+		return fmt.Errorf("Cannot run writer command %s: %s", command, err)
+	}
+
+	if !strings.Contains(string(out), "-"+WriterRunIDFlag) || !strings.Contains(string(out), "-"+WriterResultFlag) {
+		return fmt.Errorf("Writer command %s cannot run multi-writer test writers, it has no -%s and -%s flags", command, WriterRunIDFlag, WriterResultFlag)
+	}
+	return nil
+}
+
+// startWriterUnit starts j.WriterCommand in a transient unit of w.
+func startWriterUnit(conn *dbus.Conn, j JournaldTestSuite, w *writer) error {
+	command := []string{
+		j.WriterCommand,
+		"-" + WriterRunIDFlag, w.lines.runID,
+		"-" + WriterResultFlag, w.resultFile,
+		"-log-rate", strconv.Itoa(w.rate),
+		"-line-size", strconv.Itoa(j.LineSize),
+		"-duration", strconv.Itoa(j.TestDuration),
+	}
+
+	properties := []dbus.Property{
+		dbus.PropExecStart(command, false),
+		dbus.PropDescription(fmt.Sprintf("Journald scale test writer %s", w.name)),
+		{Name: "SyslogIdentifier", Value: godbus.MakeVariant(w.name)},
+	}
+
+	ch := make(chan string, 1)
+	if _, err := conn.StartTransientUnit(w.unit, "fail", properties, ch); err != nil {
+		return fmt.Errorf("Cannot start unit %s: %s", w.unit, err)
+	}
+
+	if result := <-ch; result != "done" {
+		return fmt.Errorf("Cannot start unit %s: job %s", w.unit, result)
+	}
+
+	jlog.Infof("Started writer %s at %d lines per second in %s", w.name, w.rate, w.unit)
+	return nil
+}
+
+// unitsActive returns true if any writer unit is still running.
+func unitsActive(conn *dbus.Conn, writers []*writer) bool {
+	for _, w := range writers {
+		prop, err := conn.GetUnitProperty(w.unit, "ActiveState")
+		if err != nil {
+			continue
+		}
+
+		if state, ok := prop.Value.Value().(string); ok && state != "inactive" && state != "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// reportWriters sets per writer result metrics and sends them to the supervisor as multi-writer
+// events labeled with the writer name and rate.
+func reportWriters(supervisorURL string, j JournaldTestSuite, v *verifier, writers []*writer, result *test.Result) error {
+	var written, delivered uint64
+	for i, w := range writers {
+		d, _, _ := v.runs[w.lines.runID].counts(w.written, 1)
+		written += w.written
+		delivered += uint64(d)
+
+		metric := func(name string) string {
+			return fmt.Sprintf("writer_%d_lines_%s", i, name)
+		}
+
+		events := []deliveryEvent{
+			{WRITTEN, metric("written"), float64(w.written), LINES},
+			{DELIVERED, metric("delivered"), float64(d), LINES},
+			{LOST, metric("lost"), float64(int(w.written) - d), LINES},
+		}
+
+		// goroutine writers share the unit of this process, suppressions cannot be attributed to them.
+		if w.unit != "" {
+			events = append(events, deliveryEvent{SUPPRESSED, metric("suppressed"), float64(w.suppressed), LINES})
+		}
+
+		for _, e := range events {
+			result.SetMetric(e.metric, e.value)
+
+			event, err := j.newEvent(MULTI_WRITER, e.action, e.value, e.unit)
+			if err != nil {
+				return err
+			}
+			event.SetLabel("writer", w.name)
+			event.SetLabel("writer_rate", strconv.Itoa(w.rate))
+			if w.unit != "" {
+				event.SetLabel("unit", w.unit)
+			}
+
+			if err := http.PostToSupervisor(supervisorURL, event); err != nil {
+				return err
+			}
+		}
+
+		jlog.Infof("Writer %s at %d lines per second: %d lines written, %d delivered, %d suppressed", w.name, w.rate, w.written, d, w.suppressed)
+	}
+
+	result.SetMetric("lines_written", float64(written))
+	result.SetMetric("lines_delivered", float64(delivered))
+	return nil
+}
+
+// RunWriter is the body of a multi-writer child process in the unit mode. It writes lines of
+// runID to STDOUT at rate lines per second until ctx is done, then stores the number of lines
+// written to resultFile.
+func RunWriter(ctx context.Context, runID string, rate, lineSize int, resultFile string) error {
+	if rate <= 0 {
+		return fmt.Errorf("Invalid log rate %d", rate)
+	}
+
+	lines, err := newLineSource(lineSize)
+	if err != nil {
+		return err
+	}
+	lines.runID = runID
+
+	written := writeLines(ctx, JournaldTestSuite{LineSize: lineSize, lines: lines}, rate)
+	if err := ioutil.WriteFile(resultFile, []byte(strconv.Itoa(written)), 0644); err != nil {
+		return fmt.Errorf("Cannot write writer result: %s", err)
+	}
+	return nil
+}
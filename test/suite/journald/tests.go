@@ -4,131 +4,298 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"time"
 
 	"github.com/coreos/go-systemd/journal"
-	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/mesosphere/performance/test"
 	"github.com/mesosphere/performance/test/http"
 )
 
-func newJournalSince(since time.Duration) (*sdjournal.Journal, error) {
-	journal, err := sdjournal.NewJournal()
-	if err != nil {
-		return journal, err
+// ConstantRate sends log lines to STDOUT or STDERR at a constant rate denoted by
+// j.LoggingRate until ctx is done. Lines written and the achieved rate are added to
+// result metrics.
+func ConstantRate(ctx context.Context, supervisorURL string, j JournaldTestSuite, result *test.Result) error {
+	jlog.Info("Starting constant rate test for journald")
+
+	if !journal.Enabled() {
+		return errors.New("Systemd-journald not enabled, canceling request to start test")
 	}
-	journal.Wait(since)
 
-	return journal, nil
-}
+	startEvent, err := j.newEvent(CONSTANT_RATE, START, float64(j.LoggingRate), LINES_PER_SECOND)
+	if err != nil {
+		return err
+	}
 
-// droppedLogs function is meant to be ran in asynchronous goroutine. It sends
-// events to the supervisor independently from the parent goroutine when logs
-// are detected as dropped in journald.
-func droppedLogsDetector(ctx context.Context, supervisorURL string) error {
-	jlog.Info("Starting dropped logs detection service")
-	eventName := fmt.Sprintf(TEST_SUITE + DELIMITER + "dropped-logs")
-	dropEvent, err := newEvent(eventName)
+	stopEvent, err := j.newEvent(CONSTANT_RATE, STOP, float64(j.LoggingRate), LINES_PER_SECOND)
 	if err != nil {
 		return err
 	}
 
-	lastJournalCheck := time.Since(time.Now())
-	jlog.Info("getting new journal")
+	if err := http.PostToSupervisor(supervisorURL, startEvent); err != nil {
+		return err
+	}
+
+	jlog.Infof("Starting constant rate loop:\n   Duration %d seconds\n   Rate: %d lines per second\n   Line size: %d bytes", j.TestDuration, j.LoggingRate, j.LineSize)
+
+	// writing stops early if the detector fails. suppressed is only read after the detector has exited.
+	writeCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	suppressed := 0
+	onDrop := func(s suppression) { suppressed += s.Count }
+	detectorErr := runDetector(writeCtx, stop, supervisorURL, j, j.testUnits(), onDrop)
+
+	started := time.Now()
+	written := writeLines(writeCtx, j, j.LoggingRate)
+
+	elapsed := time.Since(started).Seconds()
+	result.SetMetric("lines_written", float64(written))
+	result.SetMetric("lines_per_second", float64(written)/elapsed)
+
+	stop()
+	result.AddError(<-detectorErr)
+	result.SetMetric("lines_suppressed", float64(suppressed))
 
-	sdReader, err := newJournalSince(lastJournalCheck)
+	if ctx.Err() == context.Canceled {
+		result.AddError(errors.New("Test interrupted"))
+	}
+	return http.PostToSupervisor(supervisorURL, stopEvent)
+}
+
+// Ramp increases the logging rate from j.RampStartRate to j.LoggingRate in j.RampSteps
+// steps of equal length over the test duration. An event is sent at each step. The test
+// stops as soon as journald reports suppressed lines of the test unit. Journald reports them
+// when a rate limit window ends, so the rate of the step which was active when the window
+// began, j.RateLimitInterval earlier, is the saturation_rate result metric.
+func Ramp(ctx context.Context, supervisorURL string, j JournaldTestSuite, result *test.Result) error {
+	jlog.Info("Starting ramp test for journald")
+
+	if !journal.Enabled() {
+		return errors.New("Systemd-journald not enabled, canceling request to start test")
+	}
+
+	if j.unit == "" {
+		return errors.New("Cannot detect saturation, the test does not run in a systemd unit")
+	}
+
+	rates := rampRates(j.RampStartRate, j.LoggingRate, j.RampSteps, j.RampCurve)
+	stepDuration := time.Duration(j.TestDuration) * time.Second / time.Duration(len(rates))
+
+	startEvent, err := j.newEvent(RAMP, START, float64(j.RampStartRate), LINES_PER_SECOND)
 	if err != nil {
 		return err
 	}
 
-	jlog.Infof("Checking journal since %s", lastJournalCheck)
-	for {
+	if err := http.PostToSupervisor(supervisorURL, startEvent); err != nil {
+		return err
+	}
 
-		select {
+	jlog.Infof("Starting ramp loop:\n   Duration %d seconds\n   Rates: %v lines per second\n   Step: %s\n   Line size: %d bytes", j.TestDuration, rates, stepDuration, j.LineSize)
 
-		case <-ctx.Done():
-			jlog.Info("Canceling dropped log detector routine")
-			return nil
-
-		default:
-
-			jlog.Info("adding suppressed match")
-			if err := sdReader.AddMatch("Suppressed"); err != nil {
-				return err
-			}
-
-			jlog.Info("getting journal entry")
-			entry, err := sdReader.GetEntry()
-			if err != nil {
-				return err
-			}
-
-			jlog.Info("detecting fields from entry")
-			if len(entry.Fields) != 0 {
-				jlog.Info("DROP LOG EVENT")
-				jlog.Warnf("%+v", entry)
-				if err := http.PostToSupervisor(supervisorURL, dropEvent); err != nil {
-					return err
-				}
-			}
+	// saturated is canceled on the first dropped logs or if the detector fails. dropped and
+	// first are only read after the detector has exited.
+	saturated, stop := context.WithCancel(ctx)
+	defer stop()
+
+	dropped := false
+	var first suppression
+	onDrop := func(s suppression) {
+		if !dropped {
+			first = s
+		}
+		dropped = true
+		stop()
+	}
+	detectorErr := runDetector(saturated, stop, supervisorURL, j, j.testUnits(), onDrop)
+
+	started := time.Now()
+	written := 0
+	rate := 0
+	steps := 0
+	stepStarts := []time.Time{}
+	for _, rate = range rates {
+		stepEvent, err := j.newEvent(RAMP, STEP, float64(rate), LINES_PER_SECOND)
+		if err != nil {
+			return err
+		}
 
+		if err := http.PostToSupervisor(supervisorURL, stepEvent); err != nil {
+			return err
 		}
 
+		jlog.Infof("Ramp step %d/%d: %d lines per second", steps+1, len(rates), rate)
+		stepStarts = append(stepStarts, time.Now())
+		stepCtx, cancel := context.WithTimeout(saturated, stepDuration)
+		written += writeLines(stepCtx, j, rate)
+		cancel()
+		steps++
+
+		if saturated.Err() != nil {
+			break
+		}
 	}
 
-	return nil
+	elapsed := time.Since(started).Seconds()
+	result.SetMetric("lines_written", float64(written))
+	result.SetMetric("lines_per_second", float64(written)/elapsed)
+	result.SetMetric("steps_completed", float64(steps))
+
+	stop()
+	result.AddError(<-detectorErr)
+
+	if ctx.Err() == context.Canceled {
+		result.AddError(errors.New("Test interrupted"))
+	}
+
+	action, value := STOP, float64(rate)
+	if dropped && ctx.Err() == nil {
+		step := activeStep(stepStarts, first.Time.Add(-j.RateLimitInterval))
+		jlog.Warnf("Dropped logs detected at %d lines per second, ramp step %d/%d", rates[step], step+1, len(rates))
+		result.SetMetric("saturation_rate", float64(rates[step]))
+		action, value = SATURATED, float64(rates[step])
+	}
+
+	stopEvent, err := j.newEvent(RAMP, action, value, LINES_PER_SECOND)
+	if err != nil {
+		return err
+	}
+	return http.PostToSupervisor(supervisorURL, stopEvent)
 }
 
-// ConstantRate sends log lines to STDOUT or STDERR at a constant rate denoted by
-// j.LoggingRate. This method is canceled by the parent context when it reaches the
-// configured context.WithTimeout() which is set by JournaldTestSuite.TestDuration
-// parameter.
-func ConstantRate(supervisorURL string, j JournaldTestSuite) error {
-	jlog.Info("Starting constant rate test for journald")
+// Burst alternates bursts of j.BurstSize lines, written as fast as possible, with idle
+// gaps of j.BurstGap until ctx is done. Each burst sends a start event with the requested
+// line count and a stop event with the actual one.
+func Burst(ctx context.Context, supervisorURL string, j JournaldTestSuite, result *test.Result) error {
+	jlog.Info("Starting burst test for journald")
 
 	if !journal.Enabled() {
 		return errors.New("Systemd-journald not enabled, canceling request to start test")
 	}
 
-	rate := time.Duration(time.Second / time.Duration(j.LoggingRate))
-	ticker := time.NewTicker(rate)
+	jlog.Infof("Starting burst loop:\n   Duration %d seconds\n   Burst: %d lines\n   Gap: %s\n   Line size: %d bytes", j.TestDuration, j.BurstSize, j.BurstGap, j.LineSize)
+
+	// bursts stop early if the detector fails. suppressed is only read after the detector has exited.
+	burstCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	suppressed := 0
+	onDrop := func(s suppression) { suppressed += s.Count }
+	detectorErr := runDetector(burstCtx, stop, supervisorURL, j, j.testUnits(), onDrop)
+
+	written := 0
+	bursts := 0
+	peak := 0.0
+	for burstCtx.Err() == nil {
+		startEvent, err := j.newEvent(BURST, START, float64(j.BurstSize), LINES)
+		if err != nil {
+			return err
+		}
+
+		if err := http.PostToSupervisor(supervisorURL, startEvent); err != nil {
+			return err
+		}
+
+		started := time.Now()
+		count := 0
+		for ; count < j.BurstSize && burstCtx.Err() == nil; count++ {
+			writeLine(j)
+		}
+		elapsed := time.Since(started).Seconds()
+
+		written += count
+		bursts++
+		if elapsed > 0 && float64(count)/elapsed > peak {
+			peak = float64(count) / elapsed
+		}
+
+		stopEvent, err := j.newEvent(BURST, STOP, float64(count), LINES)
+		if err != nil {
+			return err
+		}
+		stopEvent.DurationSec = elapsed
+
+		if err := http.PostToSupervisor(supervisorURL, stopEvent); err != nil {
+			return err
+		}
 
-	line := []string{}
-	for length := 0; length <= 2048; length++ {
-		line = append(line, "0")
+		select {
+		case <-time.After(j.BurstGap):
+		case <-burstCtx.Done():
+		}
 	}
 
-	startEventName := fmt.Sprintf(TEST_SUITE+DELIMITER+"constant-rate"+DELIMITER+START+DELIMITER+"%d"+DELIMITER+LINES_PER_SECOND, j.LoggingRate)
-	stopEventName := fmt.Sprintf(TEST_SUITE+DELIMITER+"constant-rate"+DELIMITER+STOP+DELIMITER+"%d"+DELIMITER+LINES_PER_SECOND, j.LoggingRate)
+	result.SetMetric("bursts", float64(bursts))
+	result.SetMetric("lines_written", float64(written))
+	result.SetMetric("peak_lines_per_second", peak)
 
-	startEvent, err := newEvent(startEventName)
-	if err != nil {
-		return err
+	stop()
+	result.AddError(<-detectorErr)
+	result.SetMetric("lines_suppressed", float64(suppressed))
+
+	if ctx.Err() == context.Canceled {
+		result.AddError(errors.New("Test interrupted"))
 	}
+	return nil
+}
 
-	stopEvent, err := newEvent(stopEventName)
-	if err != nil {
-		return err
+// activeStep returns the index of the step which was active at t given step start times, the
+// first step if t is before the ramp started.
+func activeStep(starts []time.Time, t time.Time) int {
+	step := 0
+	for i, start := range starts {
+		if !start.After(t) {
+			step = i
+		}
+	}
+	return step
+}
+
+// rampRates returns a rate for each of steps steps from start to end. A linear curve adds
+// the same number of lines per second at each step, an exponential one multiplies the rate
+// by the same factor.
+func rampRates(start, end, steps int, curve string) []int {
+	if steps <= 1 {
+		return []int{end}
 	}
 
-	if err := http.PostToSupervisor(supervisorURL, startEvent); err != nil {
-		return err
+	rates := []int{}
+	for i := 0; i < steps; i++ {
+		fraction := float64(i) / float64(steps-1)
+
+		rate := float64(start) + float64(end-start)*fraction
+		if curve == RAMP_EXPONENTIAL {
+			rate = float64(start) * math.Pow(float64(end)/float64(start), fraction)
+		}
+		rates = append(rates, int(math.Round(rate)))
 	}
+	return rates
+}
 
-	jlog.Infof("Starting constant rate loop:\n   Duration %d seconds\n   Rate: %d lines per second", j.TestDuration, j.LoggingRate)
+// writeLine writes the next line of j.lines to STDOUT, and to STDERR if j.StdErr is set.
+func writeLine(j JournaldTestSuite) {
+	j.lines.next()
+	//journal.Print(journal.PriInfo, fmt.Sprintf("%v\n", line))
+	fmt.Fprintln(os.Stdout, j.lines.line(STREAM_STDOUT))
+	if j.StdErr {
+		journal.Print(journal.PriErr, "%s\n", j.lines.line(STREAM_STDERR))
+	}
+}
 
-	go droppedLogsDetector(j.Context, supervisorURL)
+// writeLines writes lines at rate lines per second until ctx is done and returns the number
+// of lines written.
+func writeLines(ctx context.Context, j JournaldTestSuite, rate int) int {
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
 
+	written := 0
 	for {
 		select {
 		case <-ticker.C:
-			//journal.Print(journal.PriInfo, fmt.Sprintf("%v\n", line))
-			fmt.Fprintf(os.Stdout, fmt.Sprintf("%v", line))
-			if j.StdErr {
-				journal.Print(journal.PriErr, fmt.Sprintf("%v\n", line))
-			}
-		case <-j.Context.Done():
-			return http.PostToSupervisor(supervisorURL, stopEvent)
+			writeLine(j)
+			written++
+
+		case <-ctx.Done():
+			return written
 		}
 	}
 }
@@ -0,0 +1,317 @@
+package journald
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/mesosphere/performance/test"
+	"github.com/mesosphere/performance/test/http"
+)
+
+// streams lines are written to.
+const (
+	STREAM_STDOUT = "stdout"
+	STREAM_STDERR = "stderr"
+)
+
+// delivery event actions and units.
+const (
+	DELIVERY     = "delivery"
+	DELIVERED    = "delivered"
+	LOST         = "lost"
+	DUPLICATED   = "duplicated"
+	OUT_OF_ORDER = "out-of-order"
+	MILLISECONDS = "ms"
+)
+
+// DefaultVerifyGrace is how long the verifier keeps reading the journal after the test stops writing.
+const DefaultVerifyGrace = 5 * time.Second
+
+// linePrefix starts every generated line, followed by the run ID, stream, sequence number and
+// send timestamp:
+//
+//	perf-line run=1f2e3d4c5b6a7988 stream=stdout seq=42 ts=1500000000000000000 000...
+const linePrefix = "perf-line"
+
+// lineSource generates log lines tagged for delivery verification. Sequence numbers start at 1
+// and are shared by both streams, a line written to stdout and stderr has the same number in
+// both. lineSource is not safe for concurrent use.
+type lineSource struct {
+	runID   string
+	size    int
+	padding string
+	seq     uint64
+}
+
+func newLineSource(size int) (*lineSource, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("Cannot generate run ID: %s", err)
+	}
+
+	return &lineSource{
+		runID:   hex.EncodeToString(id),
+		size:    size,
+		padding: strings.Repeat("0", size),
+	}, nil
+}
+
+// next advances the sequence number.
+func (l *lineSource) next() {
+	l.seq++
+}
+
+// line returns the current line for a stream, padded to l.size bytes including the newline.
+// Lines are never shorter than their tags.
+func (l *lineSource) line(stream string) string {
+	line := fmt.Sprintf("%s run=%s stream=%s seq=%d ts=%d ", linePrefix, l.runID, stream, l.seq, time.Now().UnixNano())
+	if pad := l.size - 1 - len(line); pad > 0 {
+		line += l.padding[:pad]
+	}
+	return line
+}
+
+// sent returns the number of lines generated per stream.
+func (l *lineSource) sent() uint64 {
+	return l.seq
+}
+
+// lineKey identifies a generated line.
+type lineKey struct {
+	stream string
+	seq    uint64
+}
+
+// delivery accounts lines of a single run read back from the journal.
+type delivery struct {
+	seen       map[lineKey]int
+	last       map[string]uint64
+	outOfOrder int
+	latencies  []float64
+}
+
+// add accounts a line sent at sent and written to the journal at received.
+func (d *delivery) add(key lineKey, sent, received time.Time) {
+	d.seen[key]++
+	if d.seen[key] > 1 {
+		return
+	}
+
+	if key.seq < d.last[key.stream] {
+		d.outOfOrder++
+	} else {
+		d.last[key.stream] = key.seq
+	}
+
+	d.latencies = append(d.latencies, received.Sub(sent).Seconds()*1000)
+}
+
+// counts returns the number of delivered, lost and duplicated lines out of sent lines
+// written to each of streams streams.
+func (d *delivery) counts(sent uint64, streams int) (delivered, lost, duplicated int) {
+	for key, count := range d.seen {
+		if key.seq == 0 || key.seq > sent {
+			continue
+		}
+		delivered++
+		duplicated += count - 1
+	}
+	return delivered, int(sent)*streams - delivered, duplicated
+}
+
+// verifier follows the journal and matches lines of one or more runs.
+type verifier struct {
+	journal *sdjournal.Journal
+	stop    chan struct{}
+	done    chan error
+	runs    map[string]*delivery
+}
+
+// pidMatch is a journal match of entries written by this process.
+func pidMatch() string {
+	return fmt.Sprintf("%s=%d", sdjournal.SD_JOURNAL_FIELD_PID, os.Getpid())
+}
+
+// startVerifier starts reading journal entries matching any of matches written from now on,
+// lines of runIDs are accounted.
+func startVerifier(matches []string, runIDs ...string) (*verifier, error) {
+	journal, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open journal: %s", err)
+	}
+
+	// matches of the same field are combined with OR.
+	for _, match := range matches {
+		if err := journal.AddMatch(match); err != nil {
+			journal.Close()
+			return nil, fmt.Errorf("Cannot add journal match: %s", err)
+		}
+	}
+
+	if err := journal.SeekTail(); err != nil {
+		journal.Close()
+		return nil, fmt.Errorf("Cannot seek journal tail: %s", err)
+	}
+	// SeekTail points after the last entry, step back so that Next returns new entries only.
+	journal.Previous()
+
+	v := &verifier{
+		journal: journal,
+		stop:    make(chan struct{}),
+		done:    make(chan error, 1),
+		runs:    map[string]*delivery{},
+	}
+
+	for _, runID := range runIDs {
+		v.runs[runID] = &delivery{
+			seen: map[lineKey]int{},
+			last: map[string]uint64{},
+		}
+	}
+
+	go func() {
+		v.done <- v.read()
+	}()
+	return v, nil
+}
+
+// read reads entries until it catches up with the journal after stop is closed.
+func (v *verifier) read() error {
+	defer v.journal.Close()
+
+	for {
+		n, err := v.journal.Next()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			select {
+			case <-v.stop:
+				return nil
+			default:
+			}
+			v.journal.Wait(time.Second)
+			continue
+		}
+
+		message, err := v.journal.GetDataValue(sdjournal.SD_JOURNAL_FIELD_MESSAGE)
+		if err != nil {
+			continue
+		}
+
+		usec, err := v.journal.GetRealtimeUsec()
+		if err != nil {
+			return err
+		}
+		v.add(message, time.Unix(0, int64(usec)*int64(time.Microsecond)))
+	}
+}
+
+// add accounts a journal message written to the journal at received.
+func (v *verifier) add(message string, received time.Time) {
+	fields := strings.Fields(message)
+	if len(fields) < 5 || fields[0] != linePrefix {
+		return
+	}
+
+	d, ok := v.runs[strings.TrimPrefix(fields[1], "run=")]
+	if !ok {
+		return
+	}
+
+	stream := strings.TrimPrefix(fields[2], "stream=")
+	seq, err := strconv.ParseUint(strings.TrimPrefix(fields[3], "seq="), 10, 64)
+	if err != nil {
+		return
+	}
+
+	ts, err := strconv.ParseInt(strings.TrimPrefix(fields[4], "ts="), 10, 64)
+	if err != nil {
+		return
+	}
+
+	d.add(lineKey{stream: stream, seq: seq}, time.Unix(0, ts), received)
+}
+
+// finish waits grace for the journal to catch up, stops reading and returns the first read error.
+func (v *verifier) finish(ctx context.Context, grace time.Duration) error {
+	select {
+	case <-time.After(grace):
+	case <-ctx.Done():
+	}
+
+	close(v.stop)
+	return <-v.done
+}
+
+// report sets delivery metrics of lines generated by j.lines on the result and sends them
+// to the supervisor as delivery events.
+func (v *verifier) report(supervisorURL string, j JournaldTestSuite, result *test.Result) error {
+	streams := 1
+	if j.StdErr {
+		streams++
+	}
+
+	d := v.runs[j.lines.runID]
+	delivered, lost, duplicated := d.counts(j.lines.sent(), streams)
+
+	events := []deliveryEvent{
+		{DELIVERED, "lines_delivered", float64(delivered), LINES},
+		{LOST, "lines_lost", float64(lost), LINES},
+		{DUPLICATED, "lines_duplicated", float64(duplicated), LINES},
+		{OUT_OF_ORDER, "lines_out_of_order", float64(d.outOfOrder), LINES},
+	}
+
+	if len(d.latencies) > 0 {
+		sort.Float64s(d.latencies)
+		events = append(events,
+			deliveryEvent{"latency-p50", "latency_p50_ms", percentile(d.latencies, 50), MILLISECONDS},
+			deliveryEvent{"latency-p90", "latency_p90_ms", percentile(d.latencies, 90), MILLISECONDS},
+			deliveryEvent{"latency-p99", "latency_p99_ms", percentile(d.latencies, 99), MILLISECONDS},
+			deliveryEvent{"latency-max", "latency_max_ms", percentile(d.latencies, 100), MILLISECONDS})
+	}
+
+	jlog.Infof("Run %s: %d lines delivered, %d lost, %d duplicated, %d out of order", j.lines.runID, delivered, lost, duplicated, d.outOfOrder)
+
+	for _, e := range events {
+		result.SetMetric(e.metric, e.value)
+
+		event, err := j.newEvent(DELIVERY, e.action, e.value, e.unit)
+		if err != nil {
+			return err
+		}
+		event.SetLabel("line_run_id", j.lines.runID)
+
+		if err := http.PostToSupervisor(supervisorURL, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveryEvent is a delivery result reported as a result metric and an event.
+type deliveryEvent struct {
+	action string
+	metric string
+	value  float64
+	unit   string
+}
+
+// percentile returns the p-th percentile of sorted values using the nearest rank.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -2,32 +2,76 @@ package test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/mesosphere/performance/supervisor/backend"
 )
 
 var slog = logrus.WithFields(logrus.Fields{
 	"suite": "scale-suite",
 })
 
-// ScaleTester represents a generic scale test suite
+// default phase timeouts, zero means no timeout.
+const (
+	DefaultSetupTimeout    = time.Minute
+	DefaultTeardownTimeout = time.Minute
+)
+
+// ScaleTester represents a generic scale test suite. Setup and Teardown prepare and clean up
+// the environment, Run executes the test and returns its result. Run must return when its context
+// is done, a run which outlives Timeouts.Run fails. Teardown is called whenever Setup was called,
+// even if Setup or Run failed.
 type ScaleTester interface {
-	Run(string) error
-	GetEvent() chan backend.BigQuerySchema
-	GetContext() context.Context
+	Name() string
+	Setup(ctx context.Context, supervisorURL string) error
+	Run(ctx context.Context, supervisorURL string) *Result
+	Teardown(ctx context.Context) error
+}
+
+// Timeouts limits each lifecycle phase of a tester through its context, testers must return
+// when the context is done. Zero means no timeout.
+type Timeouts struct {
+	Setup    time.Duration
+	Run      time.Duration
+	Teardown time.Duration
+}
+
+// Stage is a group of testers which run concurrently. All testers of a stage are set up
+// before any of them starts running.
+type Stage struct {
+	Name    string
+	Testers []ScaleTester
+
+	// CancelOnFailure cancels the other testers of the stage as soon as one fails.
+	CancelOnFailure bool
+
+	// Cooldown is a pause after the stage, before the next one starts.
+	Cooldown time.Duration
 }
 
-// ScaleTest type is a top level object abstracting the scale testing suite.
+// ScaleTest type is a top level object abstracting the scale testing suite. Stages run
+// one after another.
 type ScaleTest struct {
-	SupervisorURL string
-	Testers       []ScaleTester
+	SupervisorURL  string
+	Stages         []Stage
+	Timeouts       Timeouts
+	TesterTimeouts map[string]Timeouts
 }
 
 // NewTestSuite returns a valid SCaleTest object configured with functional
 // parameter options.
 func NewSuite(options ...TestOption) (ScaleTest, error) {
-	scaleTestOptions := ScaleTest{}
+	scaleTestOptions := ScaleTest{
+		Timeouts: Timeouts{
+			Setup:    DefaultSetupTimeout,
+			Teardown: DefaultTeardownTimeout,
+		},
+		TesterTimeouts: map[string]Timeouts{},
+	}
+
 	for _, option := range options {
 		slog.Debugf("Adding test suite option %+v", option)
 		if err := option(&scaleTestOptions); err != nil {
@@ -37,15 +81,142 @@ func NewSuite(options ...TestOption) (ScaleTest, error) {
 	return scaleTestOptions, nil
 }
 
-// ScaleTest.Start executes the test suite for all testers{}
-func (s ScaleTest) Start() error {
+// ScaleTest.Start executes the test suite for all stages and returns results of all testers.
+// A failed stage does not stop the next ones. Canceling ctx stops running testers, their
+// teardown still runs.
+func (s ScaleTest) Start(ctx context.Context) *SuiteResult {
 	slog.Info("Starting DC/OS scale test suite...")
-	for _, test := range s.Testers {
-		if err := test.Run(s.SupervisorURL); err != nil {
-			slog.Error(err)
-			continue
+	result := &SuiteResult{Passed: true}
+	for i, stage := range s.Stages {
+		for _, r := range s.runStage(ctx, stage) {
+			result.add(r)
+		}
+
+		if stage.Cooldown > 0 && i < len(s.Stages)-1 && ctx.Err() == nil {
+			slog.Infof("Cooling down for %s after stage %s", stage.Cooldown, stage.Name)
+			select {
+			case <-time.After(stage.Cooldown):
+			case <-ctx.Done():
+			}
 		}
 	}
 
-	return nil
+	return result
+}
+
+// runStage runs testers of a stage concurrently and waits for all of them.
+func (s ScaleTest) runStage(ctx context.Context, stage Stage) []*Result {
+	slog.Infof("Starting stage %s with %d testers", stage.Name, len(stage.Testers))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// start barrier, released when every tester has finished its setup.
+	ready := &sync.WaitGroup{}
+	ready.Add(len(stage.Testers))
+	barrier := func() {
+		ready.Done()
+		ready.Wait()
+	}
+
+	failed := func(tester ScaleTester) {
+		if stage.CancelOnFailure && ctx.Err() == nil {
+			slog.Warningf("Tester %s failed, canceling stage %s", tester.Name(), stage.Name)
+			cancel()
+		}
+	}
+
+	results := make([]*Result, len(stage.Testers))
+	wg := &sync.WaitGroup{}
+	for i, tester := range stage.Testers {
+		wg.Add(1)
+		go func(i int, tester ScaleTester) {
+			defer wg.Done()
+			results[i] = s.runTester(ctx, tester, barrier, failed)
+			results[i].Stage = stage.Name
+		}(i, tester)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (s ScaleTest) timeouts(tester ScaleTester) Timeouts {
+	if t, ok := s.TesterTimeouts[tester.Name()]; ok {
+		return t
+	}
+	return s.Timeouts
+}
+
+// runTester runs a single tester lifecycle. Errors of every phase are added to the result.
+// barrier is called between setup and run, failed as soon as the tester fails.
+func (s ScaleTest) runTester(ctx context.Context, tester ScaleTester, barrier func(), failed func(ScaleTester)) *Result {
+	timeouts := s.timeouts(tester)
+	started := time.Now()
+	log := slog.WithField("tester", tester.Name())
+
+	var result *Result
+
+	log.Info("Setup")
+	setupErr := withTimeout(ctx, timeouts.Setup, func(ctx context.Context) error {
+		return tester.Setup(ctx, s.SupervisorURL)
+	})
+
+	barrier()
+
+	switch {
+	case setupErr != nil:
+		result = NewResult(tester.Name())
+		result.AddError(fmt.Errorf("Setup: %s", setupErr))
+		failed(tester)
+
+	case ctx.Err() != nil:
+		result = NewResult(tester.Name())
+		result.AddError(fmt.Errorf("Canceled before run: %s", ctx.Err()))
+
+	default:
+		log.Info("Run")
+		runErr := withTimeout(ctx, timeouts.Run, func(runCtx context.Context) error {
+			result = tester.Run(runCtx, s.SupervisorURL)
+			// a stage cancellation is not a timeout of the tester.
+			if runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				return fmt.Errorf("Run: timed out after %s", timeouts.Run)
+			}
+			return nil
+		})
+
+		if result == nil {
+			result = NewResult(tester.Name())
+			result.AddError(errors.New("Run: no result returned"))
+		}
+		result.AddError(runErr)
+
+		if !result.Passed {
+			failed(tester)
+		}
+	}
+
+	log.Info("Teardown")
+	// teardown must run even if the suite is canceled.
+	teardownErr := withTimeout(context.Background(), timeouts.Teardown, func(ctx context.Context) error {
+		return tester.Teardown(ctx)
+	})
+	if teardownErr != nil {
+		result.AddError(fmt.Errorf("Teardown: %s", teardownErr))
+	}
+
+	result.Tester = tester.Name()
+	result.Started = started
+	result.DurationSec = time.Since(started).Seconds()
+	return result
+}
+
+// withTimeout calls fn with a context canceled after timeout, if timeout is not zero.
+func withTimeout(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return fn(ctx)
 }